@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"log"
 	"subscription-service/internal/config"
+	"subscription-service/internal/currency"
 	"subscription-service/internal/database"
 	"subscription-service/internal/handlers"
 	"subscription-service/internal/migrations"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/retention"
 	"subscription-service/internal/router"
+	"subscription-service/internal/service"
 )
 
 // @title Subscription Service API
@@ -32,16 +37,32 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Загрузка курсов валют
+	rates, err := currency.LoadRates(cfg.Currency)
+	if err != nil {
+		log.Fatalf("Failed to load currency rates: %v", err)
+	}
+
 	// Выполнение миграций
-	if err := migrations.Run(db); err != nil {
+	if err := migrations.Run(db, rates.Base(), cfg.Overlap); err != nil {
 		log.Fatalf("Failed to run migrations: %v", err)
 	}
 
-	// Инициализация обработчиков
-	subscriptionHandler := handlers.NewSubscriptionHandler(db)
+	// Инициализация хранилища, сервиса и обработчиков
+	subscriptionRepo := repository.NewPostgresSubscriptionRepository(db)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepo, rates, service.Options{
+		IdempotencyTTL: cfg.Idempotency.TTL,
+		OverlapPolicy:  cfg.Overlap.Policy,
+	})
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	serviceHandler := handlers.NewServiceHandler(service.NewCatalogService(subscriptionRepo))
+	userHandler := handlers.NewUserHandler(service.NewUserService(subscriptionRepo), subscriptionService)
+
+	// Очистка корзины удаленных подписок и истекших ключей идемпотентности, истечение закончившихся подписок
+	go retention.Run(context.Background(), subscriptionService, cfg.Retention)
 
 	// Настройка роутера
-	r := router.SetupRouter(subscriptionHandler)
+	r := router.SetupRouter(subscriptionHandler, serviceHandler, userHandler)
 
 	// Запуск сервера
 	log.Printf("Server starting on port %s", cfg.Server.Port)
@@ -3,14 +3,20 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
+	Server      ServerConfig      `yaml:"server"`
+	Database    DatabaseConfig    `yaml:"database"`
+	Currency    CurrencyConfig    `yaml:"currency"`
+	Retention   RetentionConfig   `yaml:"retention"`
+	Idempotency IdempotencyConfig `yaml:"idempotency"`
+	Overlap     OverlapConfig     `yaml:"overlap"`
 }
 
 type ServerConfig struct {
@@ -26,6 +32,30 @@ type DatabaseConfig struct {
 	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" envDefault:"disable"`
 }
 
+type CurrencyConfig struct {
+	Base      string             `yaml:"base" env:"CURRENCY_BASE" envDefault:"RUB"`
+	RatesFile string             `yaml:"rates_file" env:"CURRENCY_RATES_FILE"`
+	Rates     map[string]float64 `yaml:"rates"`
+}
+
+// RetentionConfig задает хранение удаленных подписок.
+// Подписки в корзине дольше DeletedDays дней удаляются безвозвратно; 0 отключает очистку.
+type RetentionConfig struct {
+	DeletedDays   int           `yaml:"deleted_days" env:"RETENTION_DELETED_DAYS"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"RETENTION_PURGE_INTERVAL" envDefault:"1h"`
+}
+
+// IdempotencyConfig задает, сколько хранится ответ на запрос с заголовком Idempotency-Key
+type IdempotencyConfig struct {
+	TTL time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+}
+
+// OverlapConfig задает политику пересечения периодов подписок одного пользователя на один сервис:
+// reject - запрещать, warn - разрешать с предупреждением, allow - разрешать (по умолчанию)
+type OverlapConfig struct {
+	Policy string `yaml:"policy" env:"OVERLAP_POLICY" envDefault:"allow"`
+}
+
 func Load() (*Config, error) {
 	// Попытка загрузить .env файл
 	_ = godotenv.Load()
@@ -88,5 +118,57 @@ func Load() (*Config, error) {
 		cfg.Database.SSLMode = "disable"
 	}
 
+	if base := os.Getenv("CURRENCY_BASE"); base != "" {
+		cfg.Currency.Base = base
+	}
+	if cfg.Currency.Base == "" {
+		cfg.Currency.Base = "RUB"
+	}
+
+	if ratesFile := os.Getenv("CURRENCY_RATES_FILE"); ratesFile != "" {
+		cfg.Currency.RatesFile = ratesFile
+	}
+
+	if deletedDays := os.Getenv("RETENTION_DELETED_DAYS"); deletedDays != "" {
+		days, err := strconv.Atoi(deletedDays)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RETENTION_DELETED_DAYS: %w", err)
+		}
+		cfg.Retention.DeletedDays = days
+	}
+
+	if purgeInterval := os.Getenv("RETENTION_PURGE_INTERVAL"); purgeInterval != "" {
+		interval, err := time.ParseDuration(purgeInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RETENTION_PURGE_INTERVAL: %w", err)
+		}
+		cfg.Retention.PurgeInterval = interval
+	}
+	if cfg.Retention.PurgeInterval <= 0 {
+		cfg.Retention.PurgeInterval = time.Hour
+	}
+
+	if ttl := os.Getenv("IDEMPOTENCY_TTL"); ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IDEMPOTENCY_TTL: %w", err)
+		}
+		cfg.Idempotency.TTL = duration
+	}
+	if cfg.Idempotency.TTL <= 0 {
+		cfg.Idempotency.TTL = 24 * time.Hour
+	}
+
+	if policy := os.Getenv("OVERLAP_POLICY"); policy != "" {
+		cfg.Overlap.Policy = policy
+	}
+	switch cfg.Overlap.Policy {
+	case "":
+		cfg.Overlap.Policy = "allow"
+	case "reject", "warn", "allow":
+	default:
+		return nil, fmt.Errorf("invalid overlap policy %q, expected reject, warn or allow", cfg.Overlap.Policy)
+	}
+
 	return cfg, nil
 }
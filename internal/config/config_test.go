@@ -0,0 +1,37 @@
+package config
+
+import "testing"
+
+func TestLoadOverlapPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{name: "default", want: "allow"},
+		{name: "reject", env: "reject", want: "reject"},
+		{name: "warn", env: "warn", want: "warn"},
+		{name: "allow", env: "allow", want: "allow"},
+		{name: "unknown", env: "deny", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OVERLAP_POLICY", tt.env)
+			cfg, err := Load()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Load() = %+v, want error", cfg.Overlap)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if cfg.Overlap.Policy != tt.want {
+				t.Errorf("policy = %q, want %q", cfg.Overlap.Policy, tt.want)
+			}
+		})
+	}
+}
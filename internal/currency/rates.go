@@ -0,0 +1,91 @@
+package currency
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"subscription-service/internal/config"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rates хранит курсы валют относительно базовой валюты.
+// Курс валюты - стоимость одной её единицы в базовой валюте.
+type Rates struct {
+	base  string
+	rates map[string]float64
+}
+
+// LoadRates собирает таблицу курсов из конфигурации и, если указан, из файла курсов.
+// Курсы из файла имеют приоритет над курсами из config.yaml.
+func LoadRates(cfg config.CurrencyConfig) (*Rates, error) {
+	r := &Rates{
+		base:  Normalize(cfg.Base),
+		rates: map[string]float64{},
+	}
+
+	for code, rate := range cfg.Rates {
+		r.rates[Normalize(code)] = rate
+	}
+
+	if cfg.RatesFile != "" {
+		data, err := os.ReadFile(cfg.RatesFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rates file: %w", err)
+		}
+		fileRates := map[string]float64{}
+		if err := yaml.Unmarshal(data, &fileRates); err != nil {
+			return nil, fmt.Errorf("failed to parse rates file: %w", err)
+		}
+		for code, rate := range fileRates {
+			r.rates[Normalize(code)] = rate
+		}
+	}
+
+	r.rates[r.base] = 1
+
+	for code, rate := range r.rates {
+		if rate <= 0 {
+			return nil, fmt.Errorf("invalid rate for %s: %v", code, rate)
+		}
+	}
+
+	return r, nil
+}
+
+// Normalize приводит код валюты к виду ISO-4217 (верхний регистр)
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Base возвращает код базовой валюты
+func (r *Rates) Base() string {
+	return r.base
+}
+
+// Supports сообщает, известен ли курс для валюты
+func (r *Rates) Supports(code string) bool {
+	_, ok := r.rates[Normalize(code)]
+	return ok
+}
+
+// Convert переводит сумму из одной валюты в другую с округлением до целого
+func (r *Rates) Convert(amount int64, from, to string) (int64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, ok := r.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := r.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+
+	return int64(math.Round(float64(amount) * fromRate / toRate)), nil
+}
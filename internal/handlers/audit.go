@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Заголовки, определяющие источник изменения для журнала
+const (
+	requestIDHeader = "X-Request-ID"
+	actorHeader     = "X-Actor"
+)
+
+// Максимальная длина заголовков в символах - размеры колонок request_id и actor журнала изменений
+const (
+	maxRequestIDLength = 128
+	maxActorLength     = 255
+)
+
+// AuditContext добавляет в контекст запроса автора изменения (X-Actor) и ID запроса (X-Request-ID).
+// Если клиент не передал ID запроса, он генерируется; ID возвращается в заголовке ответа.
+// Слишком длинные заголовки не поместятся в журнал, поэтому запрос с ними отклоняется с 400.
+func AuditContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := strings.TrimSpace(c.GetHeader(requestIDHeader))
+		actor := strings.TrimSpace(c.GetHeader(actorHeader))
+		if utf8.RuneCountInString(requestID) > maxRequestIDLength {
+			abortHeaderTooLong(c, requestIDHeader, maxRequestIDLength)
+			return
+		}
+		if utf8.RuneCountInString(actor) > maxActorLength {
+			abortHeaderTooLong(c, actorHeader, maxActorLength)
+			return
+		}
+
+		if requestID == "" {
+			requestID = uuid.NewString()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		ctx := service.WithAuditInfo(c.Request.Context(), service.AuditInfo{
+			Actor:     actor,
+			RequestID: requestID,
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// abortHeaderTooLong прерывает запрос с 400 из-за слишком длинного заголовка
+func abortHeaderTooLong(c *gin.Context, header string, limit int) {
+	c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+		"error": fmt.Sprintf("header %s must not be longer than %d characters", header, limit),
+	})
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// batchOperation переводит операцию запроса в операцию сервиса.
+// Ошибки разбора не прерывают пакет, а сохраняются в операции.
+func batchOperation(req BatchOperationRequest) service.BatchOperation {
+	op := service.BatchOperation{Op: req.Op}
+	if req.Version != nil {
+		op.IfMatch = service.VersionMatch{*req.Version}
+	}
+
+	if req.Op == service.BatchUpdate || req.Op == service.BatchDelete {
+		id, err := uuid.Parse(req.ID)
+		if err != nil {
+			op.Err = service.NewValidationError("id", "invalid format, expected UUID")
+			return op
+		}
+		op.ID = id
+	}
+
+	switch req.Op {
+	case service.BatchCreate:
+		var data CreateSubscriptionRequest
+		if err := json.Unmarshal(req.Data, &data); err != nil {
+			op.Err = service.NewValidationError("data", err.Error())
+			return op
+		}
+		if err := binding.Validator.ValidateStruct(&data); err != nil {
+			op.Err = validationError(&data, err)
+			return op
+		}
+		op.Create = service.CreateInput{
+			ServiceName:   data.ServiceName,
+			Price:         data.Price,
+			Currency:      data.Currency,
+			BillingPeriod: data.BillingPeriod,
+			BillingDay:    data.BillingDay,
+			UserID:        data.UserID,
+			StartDate:     data.StartDate,
+			EndDate:       data.EndDate,
+			TrialMonths:   data.TrialMonths,
+			IntroPrice:    data.IntroPrice,
+			IntroMonths:   data.IntroMonths,
+		}
+	case service.BatchUpdate:
+		patch, err := decodeMergePatch(req.Data)
+		if err != nil {
+			op.Err = service.NewValidationError("data", err.Error())
+			return op
+		}
+		op.Patch = patch
+	}
+	return op
+}
+
+// batchItemResponse формирует ответ на операцию пакета
+func batchItemResponse(index int, item service.BatchItemResult) BatchItemResponse {
+	resp := BatchItemResponse{Index: index, Op: item.Op, Subscription: item.Subscription, Warning: overlapWarning(item.Subscription)}
+	if item.ID != uuid.Nil {
+		resp.ID = item.ID.String()
+	}
+
+	switch {
+	case item.Aborted:
+		resp.Status = http.StatusFailedDependency
+		resp.Error = "not applied: another operation of the atomic batch failed"
+	case item.Err != nil:
+		status, body := errorResponse(item.Err, "failed to apply operation")
+		resp.Status = status
+		resp.Error = body.Error
+		resp.Fields = body.Fields
+	case item.Op == service.BatchCreate:
+		resp.Status = http.StatusCreated
+	case item.Op == service.BatchDelete:
+		resp.Status = http.StatusNoContent
+	default:
+		resp.Status = http.StatusOK
+	}
+	return resp
+}
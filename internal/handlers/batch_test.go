@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestBatchSubscriptions(t *testing.T) {
+	const (
+		unknownID = "2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a"
+		create    = `{"op":"create","data":{"service_name":"Netflix","price":800,"user_id":"` + testUserID + `","start_date":"07-2025"}}`
+		// В операциях $ID заменяется на ID подписки, созданной до пакета
+		update = `{"op":"update","id":"$ID","version":1,"data":{"price":500}}`
+		remove = `{"op":"delete","id":"$ID"}`
+	)
+
+	tests := []struct {
+		name      string
+		atomic    bool
+		ops       []string
+		statuses  []int
+		committed bool
+		// total - количество подписок после пакета, price - цена подписки, созданной до пакета
+		total int64
+		price int
+	}{
+		{
+			name:      "all applied",
+			ops:       []string{create, update},
+			statuses:  []int{http.StatusCreated, http.StatusOK},
+			committed: true,
+			total:     2,
+			price:     500,
+		},
+		{
+			name: "failed operations are skipped",
+			ops: []string{
+				create,
+				`{"op":"update","id":"$ID","version":5,"data":{"price":500}}`,
+				`{"op":"create","data":{"service_name":"Netflix","user_id":"` + testUserID + `","start_date":"07-2025"}}`,
+				`{"op":"update","id":"$ID","data":{"service_name":null}}`,
+				`{"op":"delete","id":"` + unknownID + `"}`,
+				`{"op":"delete","id":"42"}`,
+				`{"op":"rename","id":"$ID"}`,
+				update,
+			},
+			statuses: []int{
+				http.StatusCreated, http.StatusPreconditionFailed, http.StatusBadRequest, http.StatusBadRequest,
+				http.StatusNotFound, http.StatusBadRequest, http.StatusBadRequest, http.StatusOK,
+			},
+			committed: true,
+			total:     2,
+			price:     500,
+		},
+		{
+			name:      "atomic applied",
+			atomic:    true,
+			ops:       []string{create, update, `{"op":"delete","id":"$ID","version":2}`},
+			statuses:  []int{http.StatusCreated, http.StatusOK, http.StatusNoContent},
+			committed: true,
+			total:     1,
+		},
+		{
+			name:     "atomic aborted by precondition",
+			atomic:   true,
+			ops:      []string{create, update, `{"op":"delete","id":"$ID","version":1}`, create},
+			statuses: []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusPreconditionFailed, http.StatusFailedDependency},
+			total:    1,
+			price:    400,
+		},
+		{
+			name:     "atomic aborted by validation",
+			atomic:   true,
+			ops:      []string{remove, `{"op":"create","data":{"service_name":"Netflix","price":-1,"user_id":"` + testUserID + `","start_date":"07-2025"}}`},
+			statuses: []int{http.StatusFailedDependency, http.StatusBadRequest},
+			total:    1,
+			price:    400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+
+			ops := strings.ReplaceAll(strings.Join(tt.ops, ","), "$ID", sub.ID.String())
+			body := `{"atomic":` + strconv.FormatBool(tt.atomic) + `,"operations":[` + ops + `]}`
+			w := s.do(http.MethodPost, "/api/v1/subscriptions/batch", body)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got struct {
+				Atomic    bool `json:"atomic"`
+				Committed bool `json:"committed"`
+				Results   []struct {
+					Index        int                  `json:"index"`
+					Status       int                  `json:"status"`
+					ID           string               `json:"id"`
+					Subscription *models.Subscription `json:"subscription"`
+					Error        string               `json:"error"`
+				} `json:"results"`
+			}
+			decode(t, w, &got)
+			if got.Atomic != tt.atomic || got.Committed != tt.committed {
+				t.Errorf("atomic = %t, committed = %t, want %t, %t", got.Atomic, got.Committed, tt.atomic, tt.committed)
+			}
+			if len(got.Results) != len(tt.statuses) {
+				t.Fatalf("got %d results, want %d: %s", len(got.Results), len(tt.statuses), w.Body.String())
+			}
+			for i, item := range got.Results {
+				if item.Index != i || item.Status != tt.statuses[i] {
+					t.Errorf("result %d: index %d, status %d, want %d (%s)", i, item.Index, item.Status, tt.statuses[i], item.Error)
+				}
+				if failed := item.Status >= http.StatusBadRequest; failed != (item.Error != "") || (failed && item.Subscription != nil) {
+					t.Errorf("result %d: status %d with error %q and subscription %v", i, item.Status, item.Error, item.Subscription)
+				}
+			}
+
+			// Из пакета сохраняются только успешные операции, из атомарного пакета с ошибкой - ни одной
+			if total := s.total(""); total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+			if tt.price == 0 {
+				return
+			}
+			w = s.do(http.MethodGet, "/api/v1/subscriptions/"+sub.ID.String(), "")
+			var stored models.Subscription
+			decode(t, w, &stored)
+			if stored.Price != tt.price {
+				t.Errorf("price = %d, want %d", stored.Price, tt.price)
+			}
+		})
+	}
+}
+
+func TestBatchSubscriptionsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "malformed json", body: `{"operations":`},
+		{name: "no operations", body: `{"operations":[]}`},
+		{name: "too many operations", body: `{"operations":[` + strings.Repeat(`{"op":"delete","id":"42"},`, service.MaxBatchOperations) + `{"op":"delete","id":"42"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			if w := s.do(http.MethodPost, "/api/v1/subscriptions/batch", tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"subscription-service/internal/service"
+)
+
+func TestTotalCostBillingPeriods(t *testing.T) {
+	tests := []struct {
+		name     string
+		period   string
+		price    int
+		from, to string
+		amortize bool
+		want     int64
+	}{
+		{name: "monthly", period: "monthly", price: 400, from: "01-2025", to: "06-2025", want: 2400},
+		{name: "monthly amortized", period: "monthly", price: 400, from: "01-2025", to: "06-2025", amortize: true, want: 2400},
+		{name: "annual charged in renewal month", period: "annual", price: 1200, from: "01-2025", to: "06-2025", want: 1200},
+		{name: "annual without renewal month", period: "annual", price: 1200, from: "02-2025", to: "06-2025", want: 0},
+		{name: "annual amortized", period: "annual", price: 1200, from: "02-2025", to: "06-2025", amortize: true, want: 500},
+		{name: "annual amortized over a year", period: "annual", price: 1000, from: "01-2025", to: "12-2025", amortize: true, want: 1000},
+		{name: "quarterly", period: "quarterly", price: 300, from: "02-2025", to: "04-2025", want: 300},
+		{name: "quarterly amortized", period: "quarterly", price: 300, from: "02-2025", to: "03-2025", amortize: true, want: 200},
+		// Списания 1, 8, 15, 22 и 29 января 2025
+		{name: "weekly", period: "weekly", price: 100, from: "01-2025", to: "01-2025", want: 500},
+		{name: "weekly amortized over a year", period: "weekly", price: 100, from: "01-2025", to: "12-2025", amortize: true, want: 5200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			s.create(`{"service_name":"Yandex Plus","price":` + strconv.Itoa(tt.price) + `,"billing_period":"` + tt.period +
+				`","user_id":"` + testUserID + `","start_date":"01-2025"}`)
+
+			query := "?start_date=" + tt.from + "&end_date=" + tt.to
+			if tt.amortize {
+				query += "&amortize=true"
+			}
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/total-cost"+query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var body struct {
+				TotalCost int64 `json:"total_cost"`
+			}
+			decode(t, w, &body)
+			if body.TotalCost != tt.want {
+				t.Errorf("total_cost = %d, want %d", body.TotalCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestCostSeriesAmortization(t *testing.T) {
+	tests := []struct {
+		name     string
+		amortize bool
+		want     []int64
+	}{
+		{name: "charged in renewal month", want: []int64{300, 0, 0, 300}},
+		{name: "amortized", amortize: true, want: []int64{100, 100, 100, 100}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			s.create(`{"service_name":"Yandex Plus","price":300,"billing_period":"quarterly","user_id":"` + testUserID + `","start_date":"01-2025"}`)
+
+			query := "?start_date=01-2025&end_date=04-2025"
+			if tt.amortize {
+				query += "&amortize=true"
+			}
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/cost-series"+query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var body struct {
+				Series []struct {
+					Month     string `json:"month"`
+					TotalCost int64  `json:"total_cost"`
+				} `json:"series"`
+			}
+			decode(t, w, &body)
+			if len(body.Series) != len(tt.want) {
+				t.Fatalf("got %d months, want %d: %s", len(body.Series), len(tt.want), w.Body.String())
+			}
+			for i, bucket := range body.Series {
+				if bucket.TotalCost != tt.want[i] {
+					t.Errorf("%s: total_cost = %d, want %d", bucket.Month, bucket.TotalCost, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// otherCostUserID - второй пользователь costFixture
+const otherCostUserID = "0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b"
+
+// costFixture создает подписки для расчета стоимости за 01-2025 - 02-2025:
+// Yandex Plus за 800 RUB и Netflix за 20 USD у testUserID, Yandex Plus за 300 RUB у другого пользователя
+func costFixture(t *testing.T) *testServer {
+	t.Helper()
+	s := newTestServer(t, service.Options{})
+	s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	s.create(`{"service_name":"Netflix","price":10,"currency":"USD","user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	s.create(`{"service_name":"Yandex Plus","price":300,"user_id":"` + otherCostUserID + `","start_date":"02-2025"}`)
+	return s
+}
+
+func TestTotalCostGroupBy(t *testing.T) {
+	s := costFixture(t)
+
+	tests := []struct {
+		name    string
+		groupBy string
+		status  int
+		groups  []service.CostGroup
+	}{
+		{
+			name:    "service name",
+			groupBy: "service_name",
+			status:  http.StatusOK,
+			groups:  []service.CostGroup{{ServiceName: "Netflix", TotalCost: 1800, Subscriptions: 1}, {ServiceName: "Yandex Plus", TotalCost: 1100, Subscriptions: 2}},
+		},
+		{
+			name:    "user",
+			groupBy: "user_id",
+			status:  http.StatusOK,
+			groups:  []service.CostGroup{{UserID: testUserID, TotalCost: 2600, Subscriptions: 2}, {UserID: otherCostUserID, TotalCost: 300, Subscriptions: 1}},
+		},
+		{
+			name:    "month",
+			groupBy: "month",
+			status:  http.StatusOK,
+			groups:  []service.CostGroup{{Month: "02-2025", TotalCost: 1600, Subscriptions: 3}, {Month: "01-2025", TotalCost: 1300, Subscriptions: 2}},
+		},
+		{
+			name:    "service name and user",
+			groupBy: "service_name, user_id",
+			status:  http.StatusOK,
+			groups: []service.CostGroup{
+				{ServiceName: "Netflix", UserID: testUserID, TotalCost: 1800, Subscriptions: 1},
+				{ServiceName: "Yandex Plus", UserID: testUserID, TotalCost: 800, Subscriptions: 1},
+				{ServiceName: "Yandex Plus", UserID: otherCostUserID, TotalCost: 300, Subscriptions: 1},
+			},
+		},
+		{
+			name:    "repeated field",
+			groupBy: "user_id,month,user_id",
+			status:  http.StatusOK,
+			groups: []service.CostGroup{
+				{UserID: testUserID, Month: "01-2025", TotalCost: 1300, Subscriptions: 2},
+				{UserID: testUserID, Month: "02-2025", TotalCost: 1300, Subscriptions: 2},
+				{UserID: otherCostUserID, Month: "02-2025", TotalCost: 300, Subscriptions: 1},
+			},
+		},
+		{name: "unknown field", groupBy: "price", status: http.StatusBadRequest},
+		{name: "unknown among known", groupBy: "service_name,currency", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/total-cost?start_date=01-2025&end_date=02-2025&group_by="+url.QueryEscape(tt.groupBy), "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				if _, ok := errorFields(t, w)["group_by"]; !ok {
+					t.Errorf("error fields %s do not mention group_by", w.Body.String())
+				}
+				return
+			}
+
+			var body struct {
+				TotalCost int64               `json:"total_cost"`
+				Groups    []service.CostGroup `json:"groups"`
+				Breakdown []interface{}       `json:"breakdown"`
+			}
+			decode(t, w, &body)
+			if body.TotalCost != 2900 || body.Breakdown != nil {
+				t.Errorf("total_cost = %d, breakdown %v, want 2900 without breakdown", body.TotalCost, body.Breakdown)
+			}
+			// Группы упорядочены по убыванию стоимости, порядок групп с равной стоимостью не задан
+			for i := 1; i < len(body.Groups); i++ {
+				if body.Groups[i].TotalCost > body.Groups[i-1].TotalCost {
+					t.Errorf("group %d costs more than group %d: %+v", i, i-1, body.Groups)
+				}
+			}
+			got := append([]service.CostGroup(nil), body.Groups...)
+			key := func(g service.CostGroup) string { return g.ServiceName + "|" + g.UserID + "|" + g.Month }
+			sort.Slice(got, func(i, j int) bool { return key(got[i]) < key(got[j]) })
+			want := append([]service.CostGroup(nil), tt.groups...)
+			sort.Slice(want, func(i, j int) bool { return key(want[i]) < key(want[j]) })
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("groups = %+v, want %+v", body.Groups, tt.groups)
+			}
+		})
+	}
+}
+
+func TestTotalCostCurrency(t *testing.T) {
+	s := costFixture(t)
+
+	tests := []struct {
+		name     string
+		currency string
+		status   int
+		want     int64
+		// wantCurrency - валюта total_cost в ответе
+		wantCurrency string
+	}{
+		{name: "base by default", status: http.StatusOK, want: 2900, wantCurrency: "RUB"},
+		{name: "base", currency: "RUB", status: http.StatusOK, want: 2900, wantCurrency: "RUB"},
+		// Каждое списание переводится отдельно: 400 RUB = 4 USD, 300 RUB = 3 USD
+		{name: "converted", currency: "USD", status: http.StatusOK, want: 4 + 4 + 3 + 20, wantCurrency: "USD"},
+		{name: "lower case", currency: "usd", status: http.StatusOK, want: 31, wantCurrency: "USD"},
+		{name: "unknown currency", currency: "EUR", status: http.StatusBadRequest},
+		{name: "invalid code", currency: "rubles", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/total-cost?start_date=01-2025&end_date=02-2025&currency="+tt.currency, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				if _, ok := errorFields(t, w)["currency"]; !ok {
+					t.Errorf("error fields %s do not mention currency", w.Body.String())
+				}
+				return
+			}
+
+			var body struct {
+				TotalCost int64            `json:"total_cost"`
+				Currency  string           `json:"currency"`
+				Totals    map[string]int64 `json:"totals"`
+				Breakdown []struct {
+					ServiceName string `json:"service_name"`
+					Currency    string `json:"currency"`
+					Cost        int64  `json:"cost"`
+				} `json:"breakdown"`
+			}
+			decode(t, w, &body)
+			if body.TotalCost != tt.want || body.Currency != tt.wantCurrency {
+				t.Errorf("total_cost = %d %s, want %d %s", body.TotalCost, body.Currency, tt.want, tt.wantCurrency)
+			}
+			// Суммы по валютам и стоимость подписок не переводятся
+			if want := map[string]int64{"RUB": 1100, "USD": 20}; !reflect.DeepEqual(body.Totals, want) {
+				t.Errorf("totals = %v, want %v", body.Totals, want)
+			}
+			for _, item := range body.Breakdown {
+				if item.ServiceName == "Netflix" && (item.Currency != "USD" || item.Cost != 20) {
+					t.Errorf("Netflix cost = %d %s, want 20 USD", item.Cost, item.Currency)
+				}
+			}
+		})
+	}
+}
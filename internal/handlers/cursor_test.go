@@ -0,0 +1,166 @@
+package handlers_test
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+)
+
+// cursorPage - страница списка в режиме cursor
+type cursorPage struct {
+	Data       []models.Subscription `json:"data"`
+	Pagination struct {
+		Limit      int     `json:"limit"`
+		NextCursor *string `json:"next_cursor"`
+		Total      *int64  `json:"total"`
+	} `json:"pagination"`
+}
+
+func TestCursorPagination(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	const count = 23
+	created := make([]models.Subscription, 0, count)
+	for i := 0; i < count; i++ {
+		created = append(created, s.create(`{"service_name":"Service `+strconv.Itoa(i)+`","price":100,"user_id":"`+testUserID+`","start_date":"01-2025"}`))
+	}
+	// Ожидаемый порядок обхода - по created_at, затем по id
+	sort.Slice(created, func(i, j int) bool {
+		if !created[i].CreatedAt.Equal(created[j].CreatedAt) {
+			return created[i].CreatedAt.Before(created[j].CreatedAt)
+		}
+		return created[i].ID.String() < created[j].ID.String()
+	})
+
+	tests := []struct {
+		name string
+		sort string
+		desc bool
+	}{
+		{name: "default"},
+		{name: "created_at asc", sort: "created_at:asc"},
+		{name: "created_at desc", sort: "created_at:desc", desc: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make([]uuid.UUID, 0, count)
+			for _, sub := range created {
+				want = append(want, sub.ID)
+			}
+			if tt.desc {
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+			}
+
+			var got []uuid.UUID
+			cursor := ""
+			for pages := 0; ; pages++ {
+				if pages > count {
+					t.Fatal("pagination does not end")
+				}
+				query := url.Values{"pagination": {"cursor"}, "limit": {"5"}, "sort": {tt.sort}}
+				if cursor != "" {
+					query.Set("cursor", cursor)
+				}
+				w := s.do(http.MethodGet, "/api/v1/subscriptions?"+query.Encode(), "")
+				if w.Code != http.StatusOK {
+					t.Fatalf("page %d: status = %d, body %s", pages, w.Code, w.Body.String())
+				}
+				var page cursorPage
+				decode(t, w, &page)
+				if page.Pagination.Total != nil {
+					t.Errorf("page %d: total = %d, want none by default", pages, *page.Pagination.Total)
+				}
+				for _, sub := range page.Data {
+					got = append(got, sub.ID)
+				}
+				if page.Pagination.NextCursor == nil {
+					break
+				}
+				if len(page.Data) != 5 {
+					t.Errorf("page %d has %d items and a next cursor, want 5", pages, len(page.Data))
+				}
+				cursor = *page.Pagination.NextCursor
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("got %d subscriptions, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("item %d = %s, want %s: duplicate, gap or wrong order", i, got[i], want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCursorPaginationErrors(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	for i := 0; i < 3; i++ {
+		s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	}
+
+	// next возвращает курсор второй страницы для направления sort
+	next := func(sort string) string {
+		w := s.do(http.MethodGet, "/api/v1/subscriptions?pagination=cursor&limit=1&sort="+sort, "")
+		var page cursorPage
+		decode(t, w, &page)
+		if page.Pagination.NextCursor == nil {
+			t.Fatalf("no next cursor: %s", w.Body.String())
+		}
+		return url.QueryEscape(*page.Pagination.NextCursor)
+	}
+	asc, desc := next("created_at:asc"), next("created_at:desc")
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		total  bool
+	}{
+		{name: "asc cursor", query: "pagination=cursor&cursor=" + asc, status: http.StatusOK},
+		{name: "desc cursor", query: "pagination=cursor&sort=created_at:desc&cursor=" + desc, status: http.StatusOK},
+		{name: "with total", query: "pagination=cursor&with_total=true&cursor=" + asc, status: http.StatusOK, total: true},
+		{name: "not base64", query: "pagination=cursor&cursor=%21%21%21", status: http.StatusBadRequest},
+		{name: "not json", query: "pagination=cursor&cursor=" + encode("cursor"), status: http.StatusBadRequest},
+		{name: "without id", query: "pagination=cursor&cursor=" + encode(`{"c":"2025-01-01T00:00:00Z"}`), status: http.StatusBadRequest},
+		{name: "desc cursor with asc sort", query: "pagination=cursor&cursor=" + desc, status: http.StatusBadRequest},
+		{name: "asc cursor with desc sort", query: "pagination=cursor&sort=created_at:desc&cursor=" + asc, status: http.StatusBadRequest},
+		{name: "cursor with offset pagination", query: "cursor=" + asc, status: http.StatusBadRequest},
+		{name: "sort by other field", query: "pagination=cursor&sort=price:asc", status: http.StatusBadRequest},
+		{name: "unknown pagination", query: "pagination=keyset", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions?"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var page cursorPage
+			decode(t, w, &page)
+			if len(page.Data) != 2 {
+				t.Errorf("got %d items after the first one, want 2", len(page.Data))
+			}
+			if (page.Pagination.Total != nil) != tt.total {
+				t.Errorf("total = %v, want present %t", page.Pagination.Total, tt.total)
+			}
+		})
+	}
+}
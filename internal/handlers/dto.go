@@ -1,19 +1,98 @@
 package handlers
 
+import (
+	"encoding/json"
+	"time"
+
+	"subscription-service/internal/models"
+)
+
 type CreateSubscriptionRequest struct {
-	ServiceName string `json:"service_name" binding:"required" example:"Yandex Plus"`
-	Price       int    `json:"price" binding:"required,min=0" example:"400"`
-	UserID      string `json:"user_id" binding:"required,uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
-	StartDate   string `json:"start_date" binding:"required" example:"07-2025"`
-	EndDate     string `json:"end_date,omitempty" example:"12-2025"`
+	ServiceName   string `json:"service_name" binding:"required" example:"Yandex Plus"`
+	Price         int    `json:"price" binding:"required,min=0" example:"400"`
+	Currency      string `json:"currency,omitempty" binding:"omitempty,iso4217" example:"RUB"`
+	BillingPeriod string `json:"billing_period,omitempty" binding:"omitempty,oneof=weekly monthly quarterly annual" example:"monthly"`
+	BillingDay    *int   `json:"billing_day,omitempty" binding:"omitempty,min=1,max=31" example:"15"`
+	UserID        string `json:"user_id" binding:"required,uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     string `json:"start_date" binding:"required" example:"07-2025"`
+	EndDate       string `json:"end_date,omitempty" example:"12-2025"`
+	TrialMonths   int    `json:"trial_months,omitempty" binding:"min=0" example:"1"`
+	IntroPrice    *int   `json:"intro_price,omitempty" binding:"omitempty,min=0" example:"199"`
+	IntroMonths   int    `json:"intro_months,omitempty" binding:"min=0" example:"3"`
+}
+
+// ReplaceSubscriptionRequest - полное описание подписки для PUT.
+// Необязательные поля, отсутствующие в запросе, сбрасываются в значения по умолчанию.
+type ReplaceSubscriptionRequest struct {
+	ServiceName   string `json:"service_name" binding:"required" example:"Yandex Plus"`
+	Price         *int   `json:"price" binding:"required,min=0" example:"400"`
+	Currency      string `json:"currency,omitempty" binding:"omitempty,iso4217" example:"RUB"`
+	BillingPeriod string `json:"billing_period,omitempty" binding:"omitempty,oneof=weekly monthly quarterly annual" example:"monthly"`
+	BillingDay    *int   `json:"billing_day,omitempty" binding:"omitempty,min=1,max=31" example:"15"`
+	UserID        string `json:"user_id" binding:"required,uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     string `json:"start_date" binding:"required" example:"07-2025"`
+	EndDate       string `json:"end_date,omitempty" example:"12-2025"`
+	TrialMonths   int    `json:"trial_months,omitempty" binding:"min=0" example:"1"`
+	IntroPrice    *int   `json:"intro_price,omitempty" binding:"omitempty,min=0" example:"199"`
+	IntroMonths   int    `json:"intro_months,omitempty" binding:"min=0" example:"3"`
+	// EffectiveFrom - месяц, с которого действует новая цена (по умолчанию текущий)
+	EffectiveFrom string `json:"effective_from,omitempty" example:"09-2025"`
 }
 
-type UpdateSubscriptionRequest struct {
-	ServiceName string `json:"service_name,omitempty" example:"Yandex Plus"`
-	Price       *int   `json:"price,omitempty" example:"400"`
-	UserID      string `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
-	StartDate   string `json:"start_date,omitempty" example:"07-2025"`
-	EndDate     string `json:"end_date,omitempty" example:"12-2025"`
+// PatchSubscriptionRequest - документ JSON Merge Patch (RFC 7396) для PATCH.
+// Отсутствующие поля не изменяются, null очищает end_date, billing_day и intro_price и сбрасывает currency,
+// billing_period, trial_months и intro_months.
+type PatchSubscriptionRequest struct {
+	ServiceName   *string `json:"service_name,omitempty" example:"Yandex Plus"`
+	Price         *int    `json:"price,omitempty" example:"400"`
+	Currency      *string `json:"currency,omitempty" example:"RUB"`
+	BillingPeriod *string `json:"billing_period,omitempty" example:"monthly"`
+	BillingDay    *int    `json:"billing_day,omitempty" example:"15"`
+	UserID        *string `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	StartDate     *string `json:"start_date,omitempty" example:"07-2025"`
+	EndDate       *string `json:"end_date,omitempty" example:"12-2025"`
+	TrialMonths   *int    `json:"trial_months,omitempty" example:"1"`
+	IntroPrice    *int    `json:"intro_price,omitempty" example:"199"`
+	IntroMonths   *int    `json:"intro_months,omitempty" example:"3"`
+	// EffectiveFrom - месяц, с которого действует новая цена (по умолчанию текущий). Требует price
+	EffectiveFrom *string `json:"effective_from,omitempty" example:"09-2025"`
+}
+
+type DeleteSubscriptionRequest struct {
+	Hard bool `form:"hard" example:"false"`
+}
+
+type ListSubscriptionsRequest struct {
+	Page        int    `form:"page,default=1" binding:"min=1" example:"1"`
+	Limit       int    `form:"limit,default=10" binding:"min=1,max=1000" example:"10"`
+	Pagination  string `form:"pagination,default=offset" binding:"oneof=offset cursor" example:"cursor"`
+	Cursor      string `form:"cursor" example:"eyJjIjoiMjAyNS0wNy0wMVQwMDowMDowMFoiLCJpIjoiLi4uIn0"`
+	WithTotal   *bool  `form:"with_total" example:"false"`
+	UserID      string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string `form:"service_name" example:"Yandex Plus"`
+	Search      string `form:"search" example:"yandex"`
+	Currency    string `form:"currency" example:"RUB"`
+	MinPrice    *int   `form:"min_price" binding:"omitempty,min=0" example:"100"`
+	MaxPrice    *int   `form:"max_price" binding:"omitempty,min=0" example:"1000"`
+	ActiveAt    string `form:"active_at" example:"07-2025"`
+	HasEndDate  *bool  `form:"has_end_date" example:"true"`
+	Status      string `form:"status" binding:"omitempty,oneof=trial active paused cancelled expired" example:"active"`
+	Sort        string `form:"sort" example:"price:desc"`
+}
+
+// ExportSubscriptionsRequest - параметры выгрузки подписок: формат, фильтры и сортировка списка
+type ExportSubscriptionsRequest struct {
+	Format      string `form:"format,default=csv" binding:"oneof=csv jsonl" example:"csv"`
+	UserID      string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string `form:"service_name" example:"Yandex Plus"`
+	Search      string `form:"search" example:"yandex"`
+	Currency    string `form:"currency" example:"RUB"`
+	MinPrice    *int   `form:"min_price" binding:"omitempty,min=0" example:"100"`
+	MaxPrice    *int   `form:"max_price" binding:"omitempty,min=0" example:"1000"`
+	ActiveAt    string `form:"active_at" example:"07-2025"`
+	HasEndDate  *bool  `form:"has_end_date" example:"true"`
+	Status      string `form:"status" binding:"omitempty,oneof=trial active paused cancelled expired" example:"active"`
+	Sort        string `form:"sort" example:"price:desc"`
 }
 
 type TotalCostRequest struct {
@@ -21,4 +100,121 @@ type TotalCostRequest struct {
 	EndDate     string `form:"end_date" example:"12-2025"`
 	UserID      string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
 	ServiceName string `form:"service_name" example:"Yandex Plus"`
-}
\ No newline at end of file
+	GroupBy     string `form:"group_by" example:"service_name,month"`
+	Currency    string `form:"currency" example:"RUB"`
+	Amortize    bool   `form:"amortize" example:"false"`
+	// Format - json (по умолчанию) или выгрузка строк отчета в csv или jsonl
+	Format string `form:"format,default=json" binding:"oneof=json csv jsonl" example:"csv"`
+}
+
+// OverlapsRequest - фильтры поиска пересекающихся подписок
+type OverlapsRequest struct {
+	UserID      string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string `form:"service_name" example:"Yandex Plus"`
+}
+
+// EndingTrialsRequest - параметры поиска пробных подписок, пробный период которых скоро закончится
+type EndingTrialsRequest struct {
+	Days   int    `form:"days,default=7" binding:"min=1,max=366" example:"7"`
+	UserID string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+}
+
+// UpcomingChargesRequest - параметры поиска предстоящих списаний
+type UpcomingChargesRequest struct {
+	Days   int    `form:"days,default=30" binding:"min=1,max=366" example:"30"`
+	UserID string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+}
+
+type CostSeriesRequest struct {
+	StartDate   string `form:"start_date" binding:"required" example:"01-2025"`
+	EndDate     string `form:"end_date" binding:"required" example:"12-2025"`
+	UserID      string `form:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string `form:"service_name" example:"Yandex Plus"`
+	Currency    string `form:"currency" example:"RUB"`
+	Amortize    bool   `form:"amortize" example:"false"`
+}
+
+// BatchRequest - пакет операций над подписками
+type BatchRequest struct {
+	// Atomic - выполнить все операции или ни одной
+	Atomic     bool                    `json:"atomic" example:"true"`
+	Operations []BatchOperationRequest `json:"operations" binding:"required,min=1,max=1000"`
+}
+
+// BatchOperationRequest - операция пакета: create (data - как при создании),
+// update (data - JSON Merge Patch, как в PATCH) или delete
+type BatchOperationRequest struct {
+	Op string `json:"op" example:"create"`
+	// ID - подписка для update и delete
+	ID string `json:"id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Version - ожидаемая версия подписки для update и delete, аналог If-Match
+	Version *int64          `json:"version,omitempty" example:"1"`
+	Data    json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+// BatchItemResponse - результат операции пакета
+type BatchItemResponse struct {
+	Index        int                  `json:"index"`
+	Op           string               `json:"op"`
+	Status       int                  `json:"status"`
+	ID           string               `json:"id,omitempty"`
+	Subscription *models.Subscription `json:"subscription,omitempty"`
+	Error        string               `json:"error,omitempty"`
+	Fields       map[string]string    `json:"fields,omitempty"`
+	// Warning - предупреждение о пересечении подписки с другими подписками пользователя на тот же сервис
+	Warning string `json:"warning,omitempty"`
+}
+
+// ImportSubscriptionsRequest - параметры импорта CSV
+type ImportSubscriptionsRequest struct {
+	DryRun    bool   `form:"dry_run" example:"true"`
+	Delimiter string `form:"delimiter" binding:"omitempty,len=1" example:";"`
+}
+
+// ImportRowErrorResponse - ошибка строки файла импорта
+type ImportRowErrorResponse struct {
+	Row    int               `json:"row"`
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// DeletedSubscriptionResponse - подписка из корзины вместе со временем удаления
+type DeletedSubscriptionResponse struct {
+	models.Subscription
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ServiceRequest - данные сервиса каталога для создания или полной замены
+type ServiceRequest struct {
+	Name string `json:"name" binding:"required,max=255" example:"Yandex Plus"`
+	// Aliases - другие названия сервиса, которые при создании подписки заменяются на name
+	Aliases      []string `json:"aliases,omitempty" binding:"omitempty,max=50,dive,max=255" example:"Яндекс Плюс,yandex+"`
+	Category     string   `json:"category,omitempty" binding:"max=64" example:"music"`
+	DefaultPrice *int     `json:"default_price,omitempty" binding:"omitempty,min=0" example:"400"`
+	VendorURL    string   `json:"vendor_url,omitempty" binding:"omitempty,url,max=2048" example:"https://plus.yandex.ru"`
+}
+
+// ListServicesRequest - фильтры каталога сервисов
+type ListServicesRequest struct {
+	Search   string `form:"search" example:"yandex"`
+	Category string `form:"category" example:"music"`
+}
+
+// CreateUserRequest - данные нового пользователя. Без id идентификатор генерируется
+type CreateUserRequest struct {
+	ID    string `json:"id,omitempty" binding:"omitempty,uuid" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Name  string `json:"name,omitempty" binding:"max=255" example:"Иван Петров"`
+	Email string `json:"email,omitempty" binding:"omitempty,email,max=255" example:"ivan@example.com"`
+}
+
+// UpdateUserRequest - новые имя и email пользователя
+type UpdateUserRequest struct {
+	Name  string `json:"name" binding:"max=255" example:"Иван Петров"`
+	Email string `json:"email" binding:"omitempty,email,max=255" example:"ivan@example.com"`
+}
+
+// UserSummaryRequest - параметры сводки пользователя
+type UserSummaryRequest struct {
+	Currency string `form:"currency" example:"RUB"`
+	Amortize bool   `form:"amortize" example:"false"`
+}
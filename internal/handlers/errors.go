@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// errorBody - тело ответа с ошибкой
+type errorBody struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// errorResponse переводит ошибку сервиса в HTTP-статус и тело ответа.
+// Неизвестные ошибки логируются и возвращаются клиенту как 500 с сообщением fallback.
+func errorResponse(err error, fallback string) (int, errorBody) {
+	var verr *service.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		log.Printf("Validation error: %v", verr)
+		return http.StatusBadRequest, errorBody{Error: verr.Error(), Fields: verr.Fields}
+	case errors.Is(err, service.ErrNotFound):
+		log.Printf("Not found: %v", err)
+		return http.StatusNotFound, errorBody{Error: err.Error()}
+	case errors.Is(err, service.ErrPreconditionFailed):
+		log.Printf("Precondition failed: %v", err)
+		return http.StatusPreconditionFailed, errorBody{Error: err.Error()}
+	case errors.Is(err, service.ErrIdempotencyMismatch):
+		log.Printf("Idempotency key mismatch: %v", err)
+		return http.StatusUnprocessableEntity, errorBody{Error: err.Error()}
+	case errors.Is(err, service.ErrConflict):
+		log.Printf("Conflict: %v", err)
+		return http.StatusConflict, errorBody{Error: err.Error()}
+	default:
+		log.Printf("Error: %s: %v", fallback, err)
+		return http.StatusInternalServerError, errorBody{Error: fallback}
+	}
+}
+
+// respondError отправляет ответ с ошибкой сервиса
+func respondError(c *gin.Context, err error, fallback string) {
+	status, body := errorResponse(err, fallback)
+	c.JSON(status, body)
+}
+
+// parseSubscriptionID извлекает ID подписки из пути запроса.
+// При ошибке ответ уже отправлен и возвращается false.
+func parseSubscriptionID(c *gin.Context) (uuid.UUID, bool) {
+	id := c.Param("id")
+	subscriptionID, err := uuid.Parse(id)
+	if err != nil {
+		log.Printf("Error parsing subscription ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription ID format"})
+		return uuid.Nil, false
+	}
+	return subscriptionID, true
+}
+
+// parseServiceID извлекает ID сервиса каталога из пути запроса.
+// При ошибке ответ уже отправлен и возвращается false.
+func parseServiceID(c *gin.Context) (uuid.UUID, bool) {
+	serviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing service ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid service ID format"})
+		return uuid.Nil, false
+	}
+	return serviceID, true
+}
+
+// parseUserID извлекает ID пользователя из пути запроса.
+// При ошибке ответ уже отправлен и возвращается false.
+func parseUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Printf("Error parsing user ID: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID format"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
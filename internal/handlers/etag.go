@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// subscriptionETag возвращает ETag подписки вида "<версия>-<ГГГГММДД>". Дата следующего списания
+// вычисляется при формировании ответа и меняется без изменения версии, поэтому тоже входит в ETag.
+// Подписка без следующего списания получает ETag "<версия>".
+func subscriptionETag(sub *models.Subscription) string {
+	tag := strconv.FormatInt(sub.Version, 10)
+	if sub.NextChargeDate != nil {
+		tag += "-" + sub.NextChargeDate.Format("20060102")
+	}
+	return `"` + tag + `"`
+}
+
+// setETag добавляет в ответ заголовок ETag подписки
+func setETag(c *gin.Context, sub *models.Subscription) {
+	c.Header("ETag", subscriptionETag(sub))
+}
+
+// splitETags разбирает список ETag из заголовков If-Match и If-None-Match
+func splitETags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// parseIfMatch переводит заголовок If-Match в условие на версию подписки.
+// Отсутствующий заголовок и "*" не ограничивают версию. Из ETag берется только версия:
+// изменение даты следующего списания не мешает записи. Слабые ETag (W/)
+// не подходят для If-Match и, как и нераспознанные значения, ни с чем не совпадают.
+func parseIfMatch(c *gin.Context) service.VersionMatch {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := service.VersionMatch{}
+	for _, tag := range splitETags(header) {
+		if !strings.HasPrefix(tag, `"`) || !strings.HasSuffix(tag, `"`) || len(tag) < 2 {
+			continue
+		}
+		value, _, _ := strings.Cut(tag[1:len(tag)-1], "-")
+		version, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+// notModified сообщает, что ETag подписки совпадает с заголовком If-None-Match.
+// Сравнение слабое: префикс W/ игнорируется.
+func notModified(c *gin.Context, sub *models.Subscription) bool {
+	header := strings.TrimSpace(c.GetHeader("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	etag := subscriptionETag(sub)
+	for _, tag := range splitETags(header) {
+		if strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
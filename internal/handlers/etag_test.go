@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestIfNoneMatch(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2099"}`)
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "no header", status: http.StatusOK},
+		{name: "current version", header: `"1-20990701"`, status: http.StatusNotModified},
+		{name: "weak current version", header: `W/"1-20990701"`, status: http.StatusNotModified},
+		{name: "one of several", header: `"3-20990701", "1-20990701"`, status: http.StatusNotModified},
+		{name: "any", header: "*", status: http.StatusNotModified},
+		{name: "other version", header: `"2-20990701"`, status: http.StatusOK},
+		{name: "other next charge date", header: `"1-20990601"`, status: http.StatusOK},
+		{name: "version without next charge date", header: `"1"`, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"If-None-Match", tt.header}
+			}
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/"+sub.ID.String(), "", headers...)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if etag := w.Header().Get("ETag"); etag != `"1-20990701"` {
+				t.Errorf("ETag = %q, want %q", etag, `"1-20990701"`)
+			}
+			if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 response has body %q", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	const replace = `{"service_name":"Yandex Plus","price":500,"user_id":"` + testUserID + `","start_date":"07-2099"}`
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		header string
+		status int
+	}{
+		{name: "put without header", method: http.MethodPut, body: replace, status: http.StatusOK},
+		{name: "put current version", method: http.MethodPut, body: replace, header: `"1-20990701"`, status: http.StatusOK},
+		{name: "put current version without date", method: http.MethodPut, body: replace, header: `"1"`, status: http.StatusOK},
+		// If-Match сравнивает только версию: дата следующего списания могла сдвинуться
+		{name: "put current version with old date", method: http.MethodPut, body: replace, header: `"1-20990601"`, status: http.StatusOK},
+		{name: "put one of several", method: http.MethodPut, body: replace, header: `"3-20990701", "1-20990701"`, status: http.StatusOK},
+		{name: "put any", method: http.MethodPut, body: replace, header: "*", status: http.StatusOK},
+		{name: "put stale version", method: http.MethodPut, body: replace, header: `"2-20990701"`, status: http.StatusPreconditionFailed},
+		{name: "put weak version", method: http.MethodPut, body: replace, header: `W/"1-20990701"`, status: http.StatusPreconditionFailed},
+		{name: "put garbage", method: http.MethodPut, body: replace, header: "v1", status: http.StatusPreconditionFailed},
+		{name: "patch current version", method: http.MethodPatch, body: `{"price":500}`, header: `"1-20990701"`, status: http.StatusOK},
+		{name: "patch stale version", method: http.MethodPatch, body: `{"price":500}`, header: `"2-20990701"`, status: http.StatusPreconditionFailed},
+		{name: "delete current version", method: http.MethodDelete, header: `"1-20990701"`, status: http.StatusNoContent},
+		{name: "delete stale version", method: http.MethodDelete, header: `"2-20990701"`, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2099"}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+
+			var headers []string
+			if tt.header != "" {
+				headers = []string{"If-Match", tt.header}
+			}
+			w := s.do(tt.method, path, tt.body, headers...)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+
+			switch w.Code {
+			case http.StatusOK:
+				if etag := w.Header().Get("ETag"); etag != `"2-20990701"` {
+					t.Errorf("ETag = %q, want %q", etag, `"2-20990701"`)
+				}
+			case http.StatusPreconditionFailed:
+				// Отклоненное изменение не должно затронуть подписку
+				current := s.do(http.MethodGet, path, "")
+				if current.Code != http.StatusOK || current.Header().Get("ETag") != `"1-20990701"` {
+					t.Errorf("after 412: status = %d, ETag = %q, want 200, %q", current.Code, current.Header().Get("ETag"), `"1-20990701"`)
+				}
+			}
+		})
+	}
+}
+
+func TestETagFollowsNextChargeDate(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	// Версия не меняется, а дата следующего списания сдвигается со временем
+	sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	ended := s.create(`{"service_name":"Netflix","price":800,"user_id":"` + testUserID + `","start_date":"01-2025","end_date":"06-2025"}`)
+	path := "/api/v1/subscriptions/" + sub.ID.String()
+
+	w := s.do(http.MethodGet, path, "")
+	var got models.Subscription
+	decode(t, w, &got)
+	if got.NextChargeDate == nil {
+		t.Fatal("next_charge_date is not set")
+	}
+	current := `"1-` + got.NextChargeDate.Format("20060102") + `"`
+	if etag := w.Header().Get("ETag"); etag != current {
+		t.Fatalf("ETag = %q, want %q", etag, current)
+	}
+
+	// ETag, полученный до прошлого списания, не должен давать 304
+	previous := `"1-` + got.NextChargeDate.AddDate(0, -1, 0).Format("20060102") + `"`
+	if w := s.do(http.MethodGet, path, "", "If-None-Match", previous); w.Code != http.StatusOK || w.Header().Get("ETag") != current {
+		t.Errorf("stale next charge date: status = %d, ETag = %q, want 200, %q", w.Code, w.Header().Get("ETag"), current)
+	}
+	if w := s.do(http.MethodGet, path, "", "If-None-Match", current); w.Code != http.StatusNotModified {
+		t.Errorf("current ETag: status = %d, want 304", w.Code)
+	}
+	// Для If-Match важна только версия
+	if w := s.do(http.MethodPatch, path, `{"price":500}`, "If-Match", previous); w.Code != http.StatusOK {
+		t.Errorf("If-Match with stale next charge date: status = %d, want 200, body %s", w.Code, w.Body.String())
+	}
+
+	// Без даты следующего списания ETag содержит только версию
+	if w := s.do(http.MethodGet, "/api/v1/subscriptions/"+ended.ID.String(), ""); w.Header().Get("ETag") != `"1"` {
+		t.Errorf("ended subscription ETag = %q, want %q", w.Header().Get("ETag"), `"1"`)
+	}
+}
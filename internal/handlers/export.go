@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Форматы выгрузки
+const (
+	exportCSV   = "csv"
+	exportJSONL = "jsonl"
+)
+
+// exportFlushEvery - количество записей, после которого буфер выгрузки отправляется клиенту
+const exportFlushEvery = 100
+
+// exportEncoder пишет записи выгрузки в ответ в формате CSV или JSON Lines
+type exportEncoder struct {
+	c       *gin.Context
+	csv     *csv.Writer
+	json    *json.Encoder
+	pending int
+}
+
+// newExportEncoder начинает ответ-вложение filename и для CSV записывает строку заголовка
+func newExportEncoder(c *gin.Context, format, filename string, header []string) (*exportEncoder, error) {
+	e := &exportEncoder{c: c}
+	switch format {
+	case exportJSONL:
+		c.Header("Content-Type", "application/x-ndjson")
+		e.json = json.NewEncoder(c.Writer)
+	default:
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		e.csv = csv.NewWriter(c.Writer)
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+filename+"."+format+`"`)
+	c.Status(http.StatusOK)
+
+	if e.csv != nil {
+		if err := e.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+// Encode пишет запись: record - в JSON Lines, row - в CSV
+func (e *exportEncoder) Encode(record interface{}, row []string) error {
+	var err error
+	if e.csv != nil {
+		err = e.csv.Write(row)
+	} else {
+		err = e.json.Encode(record)
+	}
+	if err != nil {
+		return err
+	}
+
+	e.pending++
+	if e.pending >= exportFlushEvery {
+		return e.Flush()
+	}
+	return nil
+}
+
+// Flush отправляет клиенту записанные данные
+func (e *exportEncoder) Flush() error {
+	e.pending = 0
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	e.c.Writer.Flush()
+	return nil
+}
+
+// subscriptionExportHeader - колонки CSV-выгрузки подписок
+var subscriptionExportHeader = []string{
+	"id", "service_name", "price", "currency", "billing_period", "billing_day", "user_id",
+	"start_date", "end_date", "trial_months", "intro_price", "intro_months", "status", "version", "created_at", "updated_at",
+}
+
+// subscriptionExportRow переводит подписку в строку CSV. Даты подписки - в формате MM-YYYY, как при создании
+func subscriptionExportRow(sub *models.Subscription) []string {
+	endDate := ""
+	if sub.EndDate != nil {
+		endDate = sub.EndDate.Format("01-2006")
+	}
+	billingDay := ""
+	if sub.BillingDay != nil {
+		billingDay = strconv.Itoa(*sub.BillingDay)
+	}
+	introPrice := ""
+	if sub.IntroPrice != nil {
+		introPrice = strconv.Itoa(*sub.IntroPrice)
+	}
+	return []string{
+		sub.ID.String(),
+		sub.ServiceName,
+		strconv.Itoa(sub.Price),
+		sub.Currency,
+		sub.BillingPeriod,
+		billingDay,
+		sub.UserID.String(),
+		sub.StartDate.Format("01-2006"),
+		endDate,
+		strconv.Itoa(sub.TrialMonths),
+		introPrice,
+		strconv.Itoa(sub.IntroMonths),
+		sub.Status,
+		strconv.FormatInt(sub.Version, 10),
+		sub.CreatedAt.Format(time.RFC3339),
+		sub.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// costBreakdownHeader - колонки CSV-выгрузки стоимости по подпискам
+var costBreakdownHeader = []string{
+	"subscription_id", "service_name", "user_id", "price", "currency", "billing_period", "months", "cost",
+}
+
+// costBreakdownRow переводит стоимость подписки в строку CSV
+func costBreakdownRow(item service.CostBreakdownItem) []string {
+	return []string{
+		item.SubscriptionID,
+		item.ServiceName,
+		item.UserID,
+		strconv.Itoa(item.Price),
+		item.Currency,
+		item.BillingPeriod,
+		strconv.Itoa(item.Months),
+		strconv.FormatInt(item.Cost, 10),
+	}
+}
+
+// costGroupHeader - колонки CSV-выгрузки стоимости по группам: поля группировки, затем итоги в валюте отчета
+func costGroupHeader(groupBy []string) []string {
+	return append(append([]string{}, groupBy...), "total_cost", "currency", "subscriptions")
+}
+
+// costGroupRow переводит группу стоимости в строку CSV
+func costGroupRow(group service.CostGroup, groupBy []string, currency string) []string {
+	row := make([]string, 0, len(groupBy)+3)
+	for _, field := range groupBy {
+		switch field {
+		case "service_name":
+			row = append(row, group.ServiceName)
+		case "user_id":
+			row = append(row, group.UserID)
+		case "month":
+			row = append(row, group.Month)
+		}
+	}
+	return append(row, strconv.FormatInt(group.TotalCost, 10), currency, strconv.Itoa(group.Subscriptions))
+}
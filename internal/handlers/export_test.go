@@ -0,0 +1,203 @@
+package handlers_test
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+// exportFixture создает подписки для выгрузки: две подписки testUserID и одну - другого пользователя
+func exportFixture(t *testing.T) (*testServer, models.Subscription, models.Subscription) {
+	t.Helper()
+	s := newTestServer(t, service.Options{})
+	yandex := s.create(`{"service_name":"Yandex Plus","price":400,"billing_day":15,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+	netflix := s.create(`{"service_name":"Netflix","price":10,"currency":"USD","user_id":"` + testUserID + `","start_date":"03-2025","end_date":"12-2030"}`)
+	s.create(`{"service_name":"Spotify","price":300,"user_id":"0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b","start_date":"01-2025"}`)
+	return s, yandex, netflix
+}
+
+// checkAttachment проверяет заголовки ответа-вложения
+func checkAttachment(t *testing.T, w *httptest.ResponseRecorder, contentType, filename string) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="`+filename+`"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+}
+
+// readCSV разбирает CSV из ответа
+func readCSV(t *testing.T, w *httptest.ResponseRecorder) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv %q: %v", w.Body.String(), err)
+	}
+	return records
+}
+
+// readJSONL разбирает JSON Lines из ответа, по одному значению на строку
+func readJSONL[T any](t *testing.T, w *httptest.ResponseRecorder) []T {
+	t.Helper()
+	var records []T
+	scanner := bufio.NewScanner(w.Body)
+	for scanner.Scan() {
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decode line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestExportSubscriptionsCSV(t *testing.T) {
+	s, yandex, netflix := exportFixture(t)
+
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/export?user_id="+testUserID+"&sort=price:desc", "")
+	checkAttachment(t, w, "text/csv; charset=utf-8", "subscriptions.csv")
+
+	records := readCSV(t, w)
+	want := [][]string{
+		{"id", "service_name", "price", "currency", "billing_period", "billing_day", "user_id",
+			"start_date", "end_date", "trial_months", "intro_price", "intro_months", "status", "version", "created_at", "updated_at"},
+		{yandex.ID.String(), "Yandex Plus", "400", "RUB", "monthly", "15", testUserID, "07-2025", "", "0", "", "0", "active", "1"},
+		{netflix.ID.String(), "Netflix", "10", "USD", "monthly", "", testUserID, "03-2025", "12-2030", "0", "", "0", "active", "1"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d rows, want %d: %q", len(records), len(want), w.Body.String())
+	}
+	if !reflect.DeepEqual(records[0], want[0]) {
+		t.Errorf("header = %q, want %q", records[0], want[0])
+	}
+	for i, row := range records[1:] {
+		// created_at и updated_at зависят от времени создания
+		if len(row) != len(want[0]) || !reflect.DeepEqual(row[:len(want[i+1])], want[i+1]) {
+			t.Errorf("row %d = %q, want %q", i+1, row, want[i+1])
+		}
+	}
+}
+
+func TestExportSubscriptionsJSONL(t *testing.T) {
+	s, yandex, netflix := exportFixture(t)
+
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/export?format=jsonl&user_id="+testUserID+"&sort=price:asc", "")
+	checkAttachment(t, w, "application/x-ndjson", "subscriptions.jsonl")
+
+	records := readJSONL[models.Subscription](t, w)
+	if len(records) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(records), w.Body.String())
+	}
+	if records[0].ID != netflix.ID || records[0].Currency != "USD" || records[0].EndDate == nil {
+		t.Errorf("line 1 = %+v, want %s", records[0], netflix.ID)
+	}
+	if records[1].ID != yandex.ID || records[1].Price != 400 || records[1].BillingDay == nil || *records[1].BillingDay != 15 {
+		t.Errorf("line 2 = %+v, want %s", records[1], yandex.ID)
+	}
+}
+
+func TestExportSubscriptionsEmptyAndInvalid(t *testing.T) {
+	s, _, _ := exportFixture(t)
+
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/export?service_name=Okko", "")
+	checkAttachment(t, w, "text/csv; charset=utf-8", "subscriptions.csv")
+	if records := readCSV(t, w); len(records) != 1 || records[0][0] != "id" {
+		t.Errorf("records = %q, want only header", records)
+	}
+
+	for _, query := range []string{"?format=xml", "?sort=foo", "?user_id=42"} {
+		if w := s.do(http.MethodGet, "/api/v1/subscriptions/export"+query, ""); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, w.Code)
+		}
+	}
+}
+
+func TestExportTotalCost(t *testing.T) {
+	s, yandex, netflix := exportFixture(t)
+	// С 07-2025 по 12-2025 Yandex Plus стоит 6 * 400 RUB, Netflix - 6 * 10 USD (по курсу 90)
+	const period = "/api/v1/subscriptions/total-cost?start_date=07-2025&end_date=12-2025&user_id=" + testUserID
+
+	t.Run("breakdown csv", func(t *testing.T) {
+		w := s.do(http.MethodGet, period+"&format=csv", "")
+		checkAttachment(t, w, "text/csv; charset=utf-8", "total-cost.csv")
+
+		records := readCSV(t, w)
+		header := []string{"subscription_id", "service_name", "user_id", "price", "currency", "billing_period", "months", "cost"}
+		if len(records) != 3 || !reflect.DeepEqual(records[0], header) {
+			t.Fatalf("records = %q, want header %q and 2 rows", records, header)
+		}
+		want := map[string][]string{
+			yandex.ID.String():  {yandex.ID.String(), "Yandex Plus", testUserID, "400", "RUB", "monthly", "6", "2400"},
+			netflix.ID.String(): {netflix.ID.String(), "Netflix", testUserID, "10", "USD", "monthly", "6", "60"},
+		}
+		for _, row := range records[1:] {
+			if !reflect.DeepEqual(row, want[row[0]]) {
+				t.Errorf("row = %q, want %q", row, want[row[0]])
+			}
+		}
+	})
+
+	t.Run("groups csv", func(t *testing.T) {
+		w := s.do(http.MethodGet, period+"&format=csv&group_by=service_name", "")
+		checkAttachment(t, w, "text/csv; charset=utf-8", "total-cost.csv")
+
+		want := [][]string{
+			{"service_name", "total_cost", "currency", "subscriptions"},
+			{"Netflix", "5400", "RUB", "1"},
+			{"Yandex Plus", "2400", "RUB", "1"},
+		}
+		if records := readCSV(t, w); !reflect.DeepEqual(records, want) {
+			t.Errorf("records = %q, want %q", records, want)
+		}
+	})
+
+	t.Run("groups jsonl", func(t *testing.T) {
+		w := s.do(http.MethodGet, period+"&format=jsonl&group_by=service_name&currency=USD", "")
+		checkAttachment(t, w, "application/x-ndjson", "total-cost.jsonl")
+
+		type group struct {
+			ServiceName   string `json:"service_name"`
+			TotalCost     int64  `json:"total_cost"`
+			Currency      string `json:"currency"`
+			Subscriptions int    `json:"subscriptions"`
+		}
+		// Каждое списание Yandex Plus переводится в USD отдельно: 400 / 90 = 4
+		want := []group{{"Netflix", 60, "USD", 1}, {"Yandex Plus", 24, "USD", 1}}
+		if records := readJSONL[group](t, w); !reflect.DeepEqual(records, want) {
+			t.Errorf("records = %+v, want %+v", records, want)
+		}
+	})
+
+	t.Run("breakdown jsonl", func(t *testing.T) {
+		w := s.do(http.MethodGet, period+"&format=jsonl", "")
+		checkAttachment(t, w, "application/x-ndjson", "total-cost.jsonl")
+
+		records := readJSONL[service.CostBreakdownItem](t, w)
+		if len(records) != 2 {
+			t.Fatalf("got %d lines, want 2: %q", len(records), w.Body.String())
+		}
+		for _, item := range records {
+			if (item.ServiceName == "Netflix" && item.Cost != 60) || (item.ServiceName == "Yandex Plus" && item.Cost != 2400) {
+				t.Errorf("item = %+v", item)
+			}
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		if w := s.do(http.MethodGet, period+"&format=xml", ""); w.Code != http.StatusBadRequest || strings.HasPrefix(w.Header().Get("Content-Disposition"), "attachment") {
+			t.Errorf("status = %d, Content-Disposition %q, want 400 without attachment", w.Code, w.Header().Get("Content-Disposition"))
+		}
+	})
+}
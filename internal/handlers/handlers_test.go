@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/currency"
+	"subscription-service/internal/handlers"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/router"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testUserID - пользователь, от имени которого создаются подписки в тестах
+const testUserID = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// testServer - приложение с маршрутами API поверх хранилища в памяти
+type testServer struct {
+	t       *testing.T
+	router  *gin.Engine
+	repo    repository.SubscriptionRepository
+	service *service.SubscriptionService
+}
+
+// newTestServer создает приложение с базовой валютой RUB и курсом USD
+func newTestServer(t *testing.T, opts service.Options) *testServer {
+	t.Helper()
+	return newTestServerWithRepo(t, repository.NewMemorySubscriptionRepository(), opts)
+}
+
+// newTestServerWithRepo создает приложение поверх repo
+func newTestServerWithRepo(t *testing.T, repo repository.SubscriptionRepository, opts service.Options) *testServer {
+	t.Helper()
+	rates, err := currency.LoadRates(config.CurrencyConfig{Base: "RUB", Rates: map[string]float64{"USD": 90}})
+	if err != nil {
+		t.Fatalf("load rates: %v", err)
+	}
+
+	subscriptions := service.NewSubscriptionService(repo, rates, opts)
+	r := router.SetupRouter(
+		handlers.NewSubscriptionHandler(subscriptions),
+		handlers.NewServiceHandler(service.NewCatalogService(repo)),
+		handlers.NewUserHandler(service.NewUserService(repo), subscriptions),
+	)
+	return &testServer{t: t, router: r, repo: repo, service: subscriptions}
+}
+
+// do выполняет запрос к приложению. headers задаются парами имя, значение
+func (s *testServer) do(method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// create создает подписку и возвращает её, прерывая тест при ошибке
+func (s *testServer) create(body string) models.Subscription {
+	s.t.Helper()
+	w := s.do(http.MethodPost, "/api/v1/subscriptions", body)
+	if w.Code != http.StatusCreated {
+		s.t.Fatalf("create subscription: status %d, body %s", w.Code, w.Body.String())
+	}
+	var sub models.Subscription
+	decode(s.t, w, &sub)
+	return sub
+}
+
+// decode разбирает JSON-ответ в v
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+}
+
+// errorFields возвращает поля ошибки валидации из ответа
+func errorFields(t *testing.T, w *httptest.ResponseRecorder) map[string]string {
+	t.Helper()
+	var body struct {
+		Fields map[string]string `json:"fields"`
+	}
+	decode(t, w, &body)
+	return body.Fields
+}
+
+// total возвращает количество подписок, подходящих под фильтры списка query
+func (s *testServer) total(query string) int64 {
+	s.t.Helper()
+	w := s.do(http.MethodGet, "/api/v1/subscriptions"+query, "")
+	if w.Code != http.StatusOK {
+		s.t.Fatalf("list subscriptions: status %d, body %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Pagination struct {
+			Total int64 `json:"total"`
+		} `json:"pagination"`
+	}
+	decode(s.t, w, &body)
+	return body.Pagination.Total
+}
@@ -0,0 +1,124 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestCreateIdempotent(t *testing.T) {
+	const (
+		body    = `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`
+		other   = `{"service_name":"Yandex Plus","price":500,"user_id":"` + testUserID + `","start_date":"07-2025"}`
+		invalid = `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"13-2025"}`
+	)
+
+	// step - запрос сценария. sameAsFirst - ответ содержит подписку из первого успешного ответа
+	type step struct {
+		key         string
+		body        string
+		status      int
+		replayed    bool
+		sameAsFirst bool
+	}
+	tests := []struct {
+		name  string
+		ttl   time.Duration
+		steps []step
+		// subscriptions - количество подписок после сценария
+		subscriptions int
+	}{
+		{
+			name: "replay with same body",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k1", body: body, status: http.StatusCreated, replayed: true, sameAsFirst: true},
+				{key: "k1", body: body, status: http.StatusCreated, replayed: true, sameAsFirst: true},
+			},
+			subscriptions: 1,
+		},
+		{
+			name: "same key with other body",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k1", body: other, status: http.StatusUnprocessableEntity},
+			},
+			subscriptions: 1,
+		},
+		{
+			name: "other key",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k2", body: body, status: http.StatusCreated},
+			},
+			subscriptions: 2,
+		},
+		{
+			name: "failed request is not stored",
+			steps: []step{
+				{key: "k1", body: invalid, status: http.StatusBadRequest},
+				{key: "k1", body: body, status: http.StatusCreated},
+			},
+			subscriptions: 1,
+		},
+		{
+			name: "expired key",
+			ttl:  time.Nanosecond,
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k1", body: other, status: http.StatusCreated},
+			},
+			subscriptions: 2,
+		},
+		{
+			name: "key too long",
+			steps: []step{
+				{key: strings.Repeat("k", service.MaxIdempotencyKeyLength+1), body: body, status: http.StatusBadRequest},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{IdempotencyTTL: tt.ttl})
+			var first *models.Subscription
+			for i, st := range tt.steps {
+				w := s.do(http.MethodPost, "/api/v1/subscriptions", st.body, "Idempotency-Key", st.key)
+				if w.Code != st.status {
+					t.Fatalf("step %d: status = %d, want %d, body %s", i, w.Code, st.status, w.Body.String())
+				}
+				if replayed := w.Header().Get("Idempotent-Replayed") == "true"; replayed != st.replayed {
+					t.Errorf("step %d: replayed = %t, want %t", i, replayed, st.replayed)
+				}
+				if w.Code != http.StatusCreated {
+					continue
+				}
+
+				var sub models.Subscription
+				decode(t, w, &sub)
+				if first == nil {
+					first = &sub
+				}
+				if st.sameAsFirst && (sub.ID != first.ID || sub.Version != first.Version) {
+					t.Errorf("step %d: got subscription %s v%d, want %s v%d", i, sub.ID, sub.Version, first.ID, first.Version)
+				}
+				if etag := w.Header().Get("ETag"); etag == "" {
+					t.Errorf("step %d: missing ETag", i)
+				}
+			}
+
+			w := s.do(http.MethodGet, "/api/v1/subscriptions", "")
+			var list struct {
+				Data []models.Subscription `json:"data"`
+			}
+			decode(t, w, &list)
+			if len(list.Data) != tt.subscriptions {
+				t.Errorf("got %d subscriptions, want %d", len(list.Data), tt.subscriptions)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// importBatchSize - количество строк, импортируемых в одной транзакции
+const importBatchSize = 500
+
+// Колонки CSV-файла импорта. Порядок колонок определяется заголовком
+var (
+	importColumns         = []string{"service_name", "price", "currency", "billing_period", "billing_day", "user_id", "start_date", "end_date", "trial_months", "intro_price", "intro_months"}
+	importRequiredColumns = []string{"service_name", "price", "user_id", "start_date"}
+)
+
+// importReport - итог импорта файла
+type importReport struct {
+	Rows     int
+	Imported int
+	Errors   []ImportRowErrorResponse
+	// StoppedAt - номер строки файла, на которой импорт прервался из-за ошибки, 0 - файл обработан целиком.
+	// Строки до неё импортированы или попали в Errors, начиная с неё - не обработаны
+	StoppedAt int
+}
+
+// importResponse возвращает тело ответа с отчетом об импорте
+func importResponse(dryRun bool, report *importReport) gin.H {
+	return gin.H{
+		"dry_run":  dryRun,
+		"rows":     report.Rows,
+		"imported": report.Imported,
+		"failed":   len(report.Errors),
+		"errors":   report.Errors,
+	}
+}
+
+// importDelimiter проверяет разделитель колонок: один символ, не кавычка и не перевод строки.
+// Пустая строка дает запятую
+func importDelimiter(value string) (rune, bool) {
+	if value == "" {
+		return ',', true
+	}
+	delimiter, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || delimiter == utf8.RuneError || delimiter == '"' || delimiter == '\r' || delimiter == '\n' {
+		return 0, false
+	}
+	return delimiter, true
+}
+
+// importColumnIndex разбирает заголовок CSV и возвращает номера известных колонок
+func importColumnIndex(header []string) (map[string]int, error) {
+	known := make(map[string]bool, len(importColumns))
+	for _, column := range importColumns {
+		known[column] = true
+	}
+
+	index := make(map[string]int, len(header))
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if i == 0 {
+			// Excel добавляет BOM в начало файла
+			column = strings.TrimPrefix(column, "\ufeff")
+		}
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q, expected %s", column, strings.Join(importColumns, ", "))
+		}
+		if _, ok := index[column]; ok {
+			return nil, fmt.Errorf("duplicate column %q", column)
+		}
+		index[column] = i
+	}
+
+	for _, column := range importRequiredColumns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("missing required column %q", column)
+		}
+	}
+	return index, nil
+}
+
+// importRow переводит строку CSV в строку импорта, проверяя её по правилам CreateSubscriptionRequest
+func importRow(line int, record []string, index map[string]int) service.ImportRow {
+	row := service.ImportRow{Line: line}
+	value := func(column string) string {
+		if i, ok := index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	req := CreateSubscriptionRequest{
+		ServiceName:   value("service_name"),
+		Currency:      value("currency"),
+		BillingPeriod: value("billing_period"),
+		UserID:        value("user_id"),
+		StartDate:     value("start_date"),
+		EndDate:       value("end_date"),
+	}
+	integers := map[string]*int{"price": &req.Price, "trial_months": &req.TrialMonths, "intro_months": &req.IntroMonths}
+	optional := map[string]**int{"billing_day": &req.BillingDay, "intro_price": &req.IntroPrice}
+	for _, column := range []string{"price", "billing_day", "trial_months", "intro_price", "intro_months"} {
+		raw := value(column)
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			row.Err = service.NewValidationError(column, "must be an integer")
+			return row
+		}
+		if dst, ok := optional[column]; ok {
+			*dst = &parsed
+			continue
+		}
+		*integers[column] = parsed
+	}
+
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		row.Err = validationError(&req, err)
+		return row
+	}
+
+	row.Input = service.CreateInput{
+		ServiceName:   req.ServiceName,
+		Price:         req.Price,
+		Currency:      req.Currency,
+		BillingPeriod: req.BillingPeriod,
+		BillingDay:    req.BillingDay,
+		UserID:        req.UserID,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		TrialMonths:   req.TrialMonths,
+		IntroPrice:    req.IntroPrice,
+		IntroMonths:   req.IntroMonths,
+	}
+	return row
+}
+
+// importCSV читает CSV из r и импортирует строки пачками по importBatchSize.
+// Ошибки отдельных строк попадают в отчет, ошибка чтения файла или сохранения пачки прерывает импорт.
+// Пачки, сохраненные до ошибки, не откатываются: вместе с ошибкой возвращается отчет о них
+// с номером строки, на которой импорт остановился. Ошибка заголовка возвращается без отчета.
+// С dryRun каждая пачка проверяется в своей откатываемой транзакции: транзакция на весь файл держалась бы,
+// пока клиент загружает его. Поэтому строки не проверяются на конфликты со строками других пачек.
+func (h *SubscriptionHandler) importCSV(ctx context.Context, r io.Reader, delimiter rune, dryRun bool) (*importReport, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = delimiter
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, service.NewValidationError("file", "is empty")
+	}
+	if err != nil {
+		return nil, service.NewValidationError("file", err.Error())
+	}
+	index, err := importColumnIndex(header)
+	if err != nil {
+		return nil, service.NewValidationError("file", err.Error())
+	}
+	reader.FieldsPerRecord = len(header)
+
+	report := &importReport{Errors: []ImportRowErrorResponse{}}
+	rows := make([]service.ImportRow, 0, importBatchSize)
+	flush := func() error {
+		if len(rows) == 0 {
+			return nil
+		}
+		result, err := h.service.Import(ctx, rows, dryRun)
+		if err != nil {
+			// Строки пачки не обработаны: они не входят в отчет
+			report.Rows -= len(rows)
+			report.StoppedAt = rows[0].Line
+			return err
+		}
+		report.Imported += result.Imported
+		for _, rowErr := range result.Errors {
+			_, body := errorResponse(rowErr.Err, "failed to import row")
+			report.Errors = append(report.Errors, ImportRowErrorResponse{Row: rowErr.Line, Error: body.Error, Fields: body.Fields})
+		}
+		rows = rows[:0]
+		return nil
+	}
+
+	// Строка, следующая за последней прочитанной: на ней остановится импорт при ошибке чтения
+	nextLine := 2
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			rows = append(rows, service.ImportRow{Line: parseErr.StartLine, Err: service.NewValidationError("row", parseErr.Err.Error())})
+			nextLine = parseErr.Line + 1
+		} else if err != nil {
+			if flushErr := flush(); flushErr != nil {
+				return report, flushErr
+			}
+			report.StoppedAt = nextLine
+			return report, service.NewValidationError("file", err.Error())
+		} else {
+			line, _ := reader.FieldPos(0)
+			rows = append(rows, importRow(line, record, index))
+			last, _ := reader.FieldPos(len(record) - 1)
+			nextLine = last + 1
+		}
+		report.Rows++
+
+		if len(rows) == importBatchSize {
+			if err := flush(); err != nil {
+				return report, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return report, err
+	}
+	return report, nil
+}
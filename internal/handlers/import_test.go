@@ -0,0 +1,229 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"subscription-service/internal/repository"
+	"subscription-service/internal/service"
+)
+
+// importResult - тело ответа импорта
+type importResult struct {
+	DryRun   bool   `json:"dry_run"`
+	Rows     int    `json:"rows"`
+	Imported int    `json:"imported"`
+	Failed   int    `json:"failed"`
+	Error    string `json:"error"`
+	Errors   []struct {
+		Row    int               `json:"row"`
+		Fields map[string]string `json:"fields"`
+	} `json:"errors"`
+	StoppedAtRow int `json:"stopped_at_row"`
+}
+
+// importFile отправляет file в поле file формы на импорт с параметрами query
+func (s *testServer) importFile(query, file string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "subscriptions.csv")
+	if err != nil {
+		s.t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write([]byte(file)); err != nil {
+		s.t.Fatalf("write form file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		s.t.Fatalf("close form: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/subscriptions/import"+query, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// importRows возвращает n строк CSV с подписками без заголовка
+func importRows(n int) string {
+	var rows strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&rows, "Service %d,100,%s,01-2025\n", i, testUserID)
+	}
+	return rows.String()
+}
+
+func TestImportSubscriptions(t *testing.T) {
+	const header = "service_name,price,user_id,start_date\n"
+
+	// rowError - ожидаемая ошибка строки файла
+	type rowError struct {
+		row   int
+		field string
+	}
+	tests := []struct {
+		name     string
+		query    string
+		file     string
+		rows     int
+		imported int
+		errors   []rowError
+		// stored - количество подписок в хранилище после импорта
+		stored int64
+	}{
+		{
+			name:     "valid rows",
+			file:     header + importRows(3),
+			rows:     3,
+			imported: 3,
+			stored:   3,
+		},
+		{
+			name:     "dry run stores nothing",
+			query:    "?dry_run=true",
+			file:     header + importRows(3),
+			rows:     3,
+			imported: 3,
+		},
+		{
+			name: "row errors with line numbers",
+			file: header +
+				"Yandex Plus,400," + testUserID + ",01-2025\n" +
+				"Netflix,abc," + testUserID + ",01-2025\n" +
+				"Spotify,300,not-a-uuid,01-2025\n" +
+				"Okko,500," + testUserID + ",13-2025\n" +
+				"Kinopoisk,300\n" +
+				"Ivi,200," + testUserID + ",01-2025\n",
+			rows:     6,
+			imported: 2,
+			errors:   []rowError{{3, "price"}, {4, "user_id"}, {5, "start_date"}, {6, "row"}},
+			stored:   2,
+		},
+		{
+			name:     "row errors in dry run",
+			query:    "?dry_run=true",
+			file:     header + "Netflix,abc," + testUserID + ",01-2025\n" + importRows(1),
+			rows:     2,
+			imported: 1,
+			errors:   []rowError{{2, "price"}},
+		},
+		{
+			name:     "columns in any order with optional columns",
+			file:     "\ufeffUser_ID, start_date,price,service_name,currency,end_date\n" + testUserID + ",01-2025,10,Netflix,USD,12-2025\n",
+			rows:     1,
+			imported: 1,
+			stored:   1,
+		},
+		{
+			name:     "custom delimiter",
+			query:    "?delimiter=%3B",
+			file:     strings.ReplaceAll(header+importRows(2), ",", ";"),
+			rows:     2,
+			imported: 2,
+			stored:   2,
+		},
+		{
+			name:     "more rows than a batch",
+			file:     header + importRows(1201),
+			rows:     1201,
+			imported: 1201,
+			stored:   1201,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			w := s.importFile(tt.query, tt.file)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got importResult
+			decode(t, w, &got)
+			if got.DryRun != (tt.query == "?dry_run=true") || got.Rows != tt.rows || got.Imported != tt.imported || got.Failed != len(tt.errors) {
+				t.Errorf("report = %+v, want %d rows, %d imported, %d failed", got, tt.rows, tt.imported, len(tt.errors))
+			}
+			if len(got.Errors) != len(tt.errors) {
+				t.Fatalf("got %d row errors, want %v: %s", len(got.Errors), tt.errors, w.Body.String())
+			}
+			for i, rowErr := range got.Errors {
+				want := tt.errors[i]
+				if _, ok := rowErr.Fields[want.field]; rowErr.Row != want.row || !ok {
+					t.Errorf("error %d = row %d %v, want row %d field %q", i, rowErr.Row, rowErr.Fields, want.row, want.field)
+				}
+			}
+			if stored := s.total("?limit=1"); stored != tt.stored {
+				t.Errorf("stored %d subscriptions, want %d", stored, tt.stored)
+			}
+		})
+	}
+}
+
+func TestImportSubscriptionsRejected(t *testing.T) {
+	rows := importRows(1)
+	tests := []struct {
+		name  string
+		query string
+		file  string
+	}{
+		{name: "empty file"},
+		{name: "unknown column", file: "service_name,price,user_id,start_date,comment\n" + rows},
+		{name: "duplicate column", file: "service_name,price,user_id,start_date,price\n" + rows},
+		{name: "missing required column", file: "service_name,price,user_id\n" + rows},
+		{name: "long delimiter", query: "?delimiter=%3B%3B", file: "service_name,price,user_id,start_date\n" + rows},
+		{name: "quote delimiter", query: "?delimiter=%22", file: "service_name,price,user_id,start_date\n" + rows},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			w := s.importFile(tt.query, tt.file)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+			if stored := s.total(""); stored != 0 {
+				t.Errorf("stored %d subscriptions, want 0", stored)
+			}
+		})
+	}
+}
+
+// failingTxRepository - хранилище, в котором транзакции перестают выполняться после первых ok
+type failingTxRepository struct {
+	repository.SubscriptionRepository
+	ok int
+}
+
+func (r *failingTxRepository) InTx(ctx context.Context, fn func(repo repository.SubscriptionRepository) error) error {
+	if r.ok == 0 {
+		return errors.New("database is unavailable")
+	}
+	r.ok--
+	return r.SubscriptionRepository.InTx(ctx, fn)
+}
+
+func TestImportSubscriptionsStopped(t *testing.T) {
+	// Первая пачка сохраняется, вторая - нет
+	s := newTestServerWithRepo(t, &failingTxRepository{SubscriptionRepository: repository.NewMemorySubscriptionRepository(), ok: 1}, service.Options{})
+	w := s.importFile("", "service_name,price,user_id,start_date\n"+importRows(499)+"Netflix,abc,"+testUserID+",01-2025\n"+importRows(100))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500, body %s", w.Code, w.Body.String())
+	}
+
+	var got importResult
+	decode(t, w, &got)
+	if got.Rows != 500 || got.Imported != 499 || got.Failed != 1 || got.StoppedAtRow != 502 || got.Error == "" {
+		t.Errorf("report = %+v, want 500 rows, 499 imported, 1 failed, stopped at row 502", got)
+	}
+	if stored := s.total("?limit=1"); stored != 499 {
+		t.Errorf("stored %d subscriptions, want 499", stored)
+	}
+}
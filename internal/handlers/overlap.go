@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"strings"
+
+	"subscription-service/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// overlapWarning возвращает текст предупреждения о пересечении подписки с другими подписками
+// пользователя на тот же сервис или пустую строку, если пересечений нет
+func overlapWarning(sub *models.Subscription) string {
+	if sub == nil || len(sub.Overlaps) == 0 {
+		return ""
+	}
+	ids := make([]string, len(sub.Overlaps))
+	for i, id := range sub.Overlaps {
+		ids[i] = id.String()
+	}
+	return "subscription overlaps with subscriptions " + strings.Join(ids, ", ") + " of the same user and service"
+}
+
+// setOverlapWarning добавляет в ответ заголовок Warning, если подписка пересекается с другими
+func setOverlapWarning(c *gin.Context, sub *models.Subscription) {
+	if warning := overlapWarning(sub); warning != "" {
+		c.Header("Warning", `299 - "`+warning+`"`)
+	}
+}
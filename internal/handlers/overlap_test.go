@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+// subscriptionBody возвращает тело запроса на подписку пользователя на сервис за период [start, end]
+func subscriptionBody(userID, serviceName, start, end string) string {
+	body := `{"service_name":"` + serviceName + `","price":400,"user_id":"` + userID + `","start_date":"` + start + `"`
+	if end != "" {
+		body += `,"end_date":"` + end + `"`
+	}
+	return body + "}"
+}
+
+func TestOverlapPolicies(t *testing.T) {
+	const otherUserID = "0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b"
+	// Первая подписка действует с 01-2025 по 06-2025 включительно
+	first := subscriptionBody(testUserID, "Yandex Plus", "01-2025", "06-2025")
+
+	tests := []struct {
+		name    string
+		policy  string
+		second  string
+		status  int
+		warning bool
+	}{
+		{name: "allowed by default", second: subscriptionBody(testUserID, "Yandex Plus", "03-2025", ""), status: http.StatusCreated},
+		{name: "allow", policy: service.OverlapAllow, second: subscriptionBody(testUserID, "Yandex Plus", "03-2025", ""), status: http.StatusCreated},
+		{name: "warn", policy: service.OverlapWarn, second: subscriptionBody(testUserID, "Yandex Plus", "03-2025", ""), status: http.StatusCreated, warning: true},
+		{name: "reject", policy: service.OverlapReject, second: subscriptionBody(testUserID, "Yandex Plus", "03-2025", ""), status: http.StatusConflict},
+		{name: "reject shared end month", policy: service.OverlapReject, second: subscriptionBody(testUserID, "Yandex Plus", "06-2025", ""), status: http.StatusConflict},
+		{name: "reject adjacent period", policy: service.OverlapReject, second: subscriptionBody(testUserID, "Yandex Plus", "07-2025", ""), status: http.StatusCreated},
+		{name: "reject other user", policy: service.OverlapReject, second: subscriptionBody(otherUserID, "Yandex Plus", "03-2025", ""), status: http.StatusCreated},
+		{name: "reject other service", policy: service.OverlapReject, second: subscriptionBody(testUserID, "Netflix", "03-2025", ""), status: http.StatusCreated},
+		{name: "warn without overlap", policy: service.OverlapWarn, second: subscriptionBody(testUserID, "Yandex Plus", "07-2025", ""), status: http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{OverlapPolicy: tt.policy})
+			sub := s.create(first)
+
+			w := s.do(http.MethodPost, "/api/v1/subscriptions", tt.second)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			warning := w.Header().Get("Warning")
+			if tt.warning != (warning != "") {
+				t.Fatalf("Warning = %q, want warning %t", warning, tt.warning)
+			}
+			if tt.warning && !strings.Contains(warning, sub.ID.String()) {
+				t.Errorf("Warning %q does not mention overlapping subscription %s", warning, sub.ID)
+			}
+		})
+	}
+}
+
+func TestOverlapPolicyOnUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy string
+		patch  string
+		status int
+	}{
+		{name: "reject moves into overlap", policy: service.OverlapReject, patch: `{"start_date":"05-2025"}`, status: http.StatusConflict},
+		{name: "reject keeps clear", policy: service.OverlapReject, patch: `{"start_date":"08-2025"}`, status: http.StatusOK},
+		{name: "reject price change keeps existing period", policy: service.OverlapReject, patch: `{"price":500}`, status: http.StatusOK},
+		{name: "allow moves into overlap", policy: service.OverlapAllow, patch: `{"start_date":"05-2025"}`, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{OverlapPolicy: tt.policy})
+			s.create(subscriptionBody(testUserID, "Yandex Plus", "01-2025", "06-2025"))
+			second := s.create(subscriptionBody(testUserID, "Yandex Plus", "07-2025", ""))
+
+			w := s.do(http.MethodPatch, "/api/v1/subscriptions/"+second.ID.String(), tt.patch)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestSubscriptionOverlaps(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	a := s.create(subscriptionBody(testUserID, "Yandex Plus", "01-2025", "06-2025"))
+	b := s.create(subscriptionBody(testUserID, "Yandex Plus", "03-2025", "09-2025"))
+	s.create(subscriptionBody(testUserID, "Yandex Plus", "10-2025", ""))
+	s.create(subscriptionBody(testUserID, "Netflix", "01-2025", ""))
+
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/overlaps?user_id="+testUserID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []struct {
+			From          string                `json:"from"`
+			To            string                `json:"to"`
+			Subscriptions []models.Subscription `json:"subscriptions"`
+		} `json:"data"`
+	}
+	decode(t, w, &body)
+	if len(body.Data) != 1 {
+		t.Fatalf("got %d overlaps, want 1: %s", len(body.Data), w.Body.String())
+	}
+	overlap := body.Data[0]
+	if overlap.From != "03-2025" || overlap.To != "06-2025" {
+		t.Errorf("overlap = %s..%s, want 03-2025..06-2025", overlap.From, overlap.To)
+	}
+	if len(overlap.Subscriptions) != 2 || overlap.Subscriptions[0].ID != a.ID || overlap.Subscriptions[1].ID != b.ID {
+		t.Errorf("overlapping subscriptions = %+v, want %s and %s", overlap.Subscriptions, a.ID, b.ID)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergePatchContentType - тип содержимого JSON Merge Patch (RFC 7396)
+const mergePatchContentType = "application/merge-patch+json"
+
+// errUnsupportedContentType возвращается для тела PATCH с неподдерживаемым Content-Type
+var errUnsupportedContentType = errors.New("unsupported content type")
+
+// parseMergePatch разбирает тело запроса PATCH в service.PatchInput.
+// Отсутствующее поле не изменяется, null очищает его; неизвестные поля отклоняются.
+func parseMergePatch(c *gin.Context) (service.PatchInput, error) {
+	var patch service.PatchInput
+
+	if contentType := c.GetHeader("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != gin.MIMEJSON) {
+			return patch, fmt.Errorf("%w %q, expected %s", errUnsupportedContentType, contentType, mergePatchContentType)
+		}
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return patch, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return decodeMergePatch(body)
+}
+
+// decodeMergePatch разбирает документ JSON Merge Patch в service.PatchInput
+func decodeMergePatch(body []byte) (service.PatchInput, error) {
+	var patch service.PatchInput
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '{' {
+		return patch, errors.New("merge patch must be a JSON object")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return patch, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var err error
+	for name, raw := range fields {
+		switch name {
+		case "service_name":
+			err = decodePatchField(raw, &patch.ServiceName)
+		case "price":
+			err = decodePatchField(raw, &patch.Price)
+		case "currency":
+			err = decodePatchField(raw, &patch.Currency)
+		case "billing_period":
+			err = decodePatchField(raw, &patch.BillingPeriod)
+		case "billing_day":
+			err = decodePatchField(raw, &patch.BillingDay)
+		case "user_id":
+			err = decodePatchField(raw, &patch.UserID)
+		case "start_date":
+			err = decodePatchField(raw, &patch.StartDate)
+		case "end_date":
+			err = decodePatchField(raw, &patch.EndDate)
+		case "trial_months":
+			err = decodePatchField(raw, &patch.TrialMonths)
+		case "intro_price":
+			err = decodePatchField(raw, &patch.IntroPrice)
+		case "intro_months":
+			err = decodePatchField(raw, &patch.IntroMonths)
+		case "effective_from":
+			err = decodePatchField(raw, &patch.EffectiveFrom)
+		default:
+			return patch, fmt.Errorf("unknown field %q", name)
+		}
+		if err != nil {
+			return patch, fmt.Errorf("invalid value for field %q: %w", name, err)
+		}
+	}
+
+	return patch, nil
+}
+
+// decodePatchField декодирует значение одного поля патча
+func decodePatchField[T any](raw json.RawMessage, field *service.PatchField[T]) error {
+	field.Set = true
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		field.Null = true
+		return nil
+	}
+	return json.Unmarshal(raw, &field.Value)
+}
@@ -0,0 +1,170 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestPatchSubscription(t *testing.T) {
+	// patched - поля подписки, которые проверяются после PATCH
+	type patched struct {
+		serviceName   string
+		price         int
+		currency      string
+		billingPeriod string
+		billingDay    int
+		endDate       bool
+	}
+	// Подписка до изменения
+	initial := patched{serviceName: "Netflix", price: 10, currency: "USD", billingPeriod: "annual", billingDay: 15, endDate: true}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		field       string
+		want        patched
+	}{
+		{
+			name:   "absent fields are kept",
+			body:   `{"price":12}`,
+			status: http.StatusOK,
+			want:   patched{serviceName: "Netflix", price: 12, currency: "USD", billingPeriod: "annual", billingDay: 15, endDate: true},
+		},
+		{
+			name:   "null clears end date",
+			body:   `{"end_date":null}`,
+			status: http.StatusOK,
+			want:   patched{serviceName: "Netflix", price: 10, currency: "USD", billingPeriod: "annual", billingDay: 15},
+		},
+		{
+			name:   "null resets optional fields",
+			body:   `{"currency":null,"billing_period":null,"billing_day":null,"price":900}`,
+			status: http.StatusOK,
+			want:   patched{serviceName: "Netflix", price: 900, currency: "RUB", billingPeriod: "monthly", endDate: true},
+		},
+		{
+			name:        "merge patch content type",
+			contentType: "application/merge-patch+json; charset=utf-8",
+			body:        `{"service_name":"Netflix Premium"}`,
+			status:      http.StatusOK,
+			want:        patched{serviceName: "Netflix Premium", price: 10, currency: "USD", billingPeriod: "annual", billingDay: 15, endDate: true},
+		},
+		{
+			name:   "empty patch",
+			body:   `{}`,
+			status: http.StatusOK,
+			want:   initial,
+		},
+		{name: "null service name", body: `{"service_name":null}`, status: http.StatusBadRequest, field: "service_name", want: initial},
+		{name: "null price", body: `{"price":null,"end_date":null}`, status: http.StatusBadRequest, field: "price", want: initial},
+		{name: "null start date", body: `{"start_date":null}`, status: http.StatusBadRequest, field: "start_date", want: initial},
+		{name: "unknown field", body: `{"price":12,"comment":"family"}`, status: http.StatusBadRequest, want: initial},
+		{name: "wrong type", body: `{"price":"12"}`, status: http.StatusBadRequest, want: initial},
+		{name: "not an object", body: `[{"price":12}]`, status: http.StatusBadRequest, want: initial},
+		{name: "invalid value", body: `{"billing_day":40}`, status: http.StatusBadRequest, field: "billing_day", want: initial},
+		{name: "unsupported content type", contentType: "text/plain", body: `{"price":12}`, status: http.StatusUnsupportedMediaType, want: initial},
+		{name: "json patch content type", contentType: "application/json-patch+json", body: `{"price":12}`, status: http.StatusUnsupportedMediaType, want: initial},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Netflix","price":10,"currency":"USD","billing_period":"annual","billing_day":15,"user_id":"` +
+				testUserID + `","start_date":"07-2025","end_date":"12-2030"}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+
+			var headers []string
+			if tt.contentType != "" {
+				headers = []string{"Content-Type", tt.contentType}
+			}
+			w := s.do(http.MethodPatch, path, tt.body, headers...)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.field != "" {
+				if _, ok := errorFields(t, w)[tt.field]; !ok {
+					t.Errorf("error fields %s do not mention %q", w.Body.String(), tt.field)
+				}
+			}
+
+			w = s.do(http.MethodGet, path, "")
+			var got models.Subscription
+			decode(t, w, &got)
+			billingDay := 0
+			if got.BillingDay != nil {
+				billingDay = *got.BillingDay
+			}
+			stored := patched{
+				serviceName:   got.ServiceName,
+				price:         got.Price,
+				currency:      got.Currency,
+				billingPeriod: got.BillingPeriod,
+				billingDay:    billingDay,
+				endDate:       got.EndDate != nil,
+			}
+			if stored != tt.want {
+				t.Errorf("subscription = %+v, want %+v", stored, tt.want)
+			}
+			// Отклоненный патч не меняет версию
+			wantVersion := sub.Version
+			if tt.status == http.StatusOK {
+				wantVersion++
+			}
+			if got.Version != wantVersion {
+				t.Errorf("version = %d, want %d", got.Version, wantVersion)
+			}
+		})
+	}
+}
+
+func TestPatchDateOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		// field - поле, о котором сообщает ошибка, other - поле, которого в ошибке быть не должно
+		field, other string
+	}{
+		{name: "start after end", method: http.MethodPatch, body: `{"start_date":"08-2025"}`, status: http.StatusBadRequest, field: "start_date", other: "end_date"},
+		{name: "end before start", method: http.MethodPatch, body: `{"end_date":"01-2025"}`, status: http.StatusBadRequest, field: "end_date", other: "start_date"},
+		{name: "both dates", method: http.MethodPatch, body: `{"start_date":"08-2025","end_date":"07-2025"}`, status: http.StatusBadRequest, field: "end_date", other: "start_date"},
+		{
+			name:   "put",
+			method: http.MethodPut,
+			body:   `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"08-2025","end_date":"06-2025"}`,
+			status: http.StatusBadRequest,
+			field:  "end_date",
+			other:  "start_date",
+		},
+		{name: "start equal to end", method: http.MethodPatch, body: `{"start_date":"06-2025"}`, status: http.StatusOK},
+		{name: "start after cleared end", method: http.MethodPatch, body: `{"start_date":"08-2025","end_date":null}`, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"03-2025","end_date":"06-2025"}`)
+
+			w := s.do(tt.method, "/api/v1/subscriptions/"+sub.ID.String(), tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code == http.StatusOK {
+				return
+			}
+			fields := errorFields(t, w)
+			if _, ok := fields[tt.field]; !ok {
+				t.Errorf("error fields %v do not mention %q", fields, tt.field)
+			}
+			if _, ok := fields[tt.other]; ok {
+				t.Errorf("error fields %v mention %q", fields, tt.other)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestPriceTimeline(t *testing.T) {
+	const replace = `"service_name":"Yandex Plus","user_id":"` + testUserID + `","start_date":"01-2025"`
+	month := func(year int, m time.Month) time.Time {
+		return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+		// price - поле price подписки после изменения, prices - шкала цен
+		price  int
+		prices []models.SubscriptionPrice
+		// total - стоимость с 01-2025 по 06-2025
+		total int64
+	}{
+		{
+			name:   "patch from month",
+			method: http.MethodPatch,
+			body:   `{"price":500,"effective_from":"04-2025"}`,
+			status: http.StatusOK,
+			price:  500,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}, {EffectiveFrom: month(2025, time.April), Price: 500}},
+			total:  3*400 + 3*500,
+		},
+		{
+			name:   "put from month",
+			method: http.MethodPut,
+			body:   `{` + replace + `,"price":500,"effective_from":"03-2025"}`,
+			status: http.StatusOK,
+			price:  500,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}, {EffectiveFrom: month(2025, time.March), Price: 500}},
+			total:  2*400 + 4*500,
+		},
+		{
+			name:   "clamped to start date",
+			method: http.MethodPatch,
+			body:   `{"price":500,"effective_from":"06-2024"}`,
+			status: http.StatusOK,
+			price:  500,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 500}},
+			total:  6 * 500,
+		},
+		{
+			name:   "current month by default",
+			method: http.MethodPut,
+			body:   `{` + replace + `,"price":500}`,
+			status: http.StatusOK,
+			price:  500,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}, {EffectiveFrom: monthStartNow(), Price: 500}},
+			total:  6 * 400,
+		},
+		{
+			// Поле price сразу содержит новую цену, стоимость считается по шкале
+			name:   "future month",
+			method: http.MethodPatch,
+			body:   `{"price":500,"effective_from":"01-2099"}`,
+			status: http.StatusOK,
+			price:  500,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}, {EffectiveFrom: month(2099, time.January), Price: 500}},
+			total:  6 * 400,
+		},
+		{
+			name:   "effective from without price",
+			method: http.MethodPatch,
+			body:   `{"effective_from":"04-2025"}`,
+			status: http.StatusBadRequest,
+			price:  400,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}},
+			total:  6 * 400,
+		},
+		{
+			name:   "invalid effective from",
+			method: http.MethodPatch,
+			body:   `{"price":500,"effective_from":"2025-04"}`,
+			status: http.StatusBadRequest,
+			price:  400,
+			prices: []models.SubscriptionPrice{{EffectiveFrom: month(2025, time.January), Price: 400}},
+			total:  6 * 400,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{` + replace + `,"price":400}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+
+			w := s.do(tt.method, path, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+
+			w = s.do(http.MethodGet, path, "")
+			var got models.Subscription
+			decode(t, w, &got)
+			if got.Price != tt.price {
+				t.Errorf("price = %d, want %d", got.Price, tt.price)
+			}
+			// Фильтры списка работают по полю price
+			if total := s.total("?min_price=" + strconv.Itoa(tt.price) + "&max_price=" + strconv.Itoa(tt.price)); total != 1 {
+				t.Errorf("list by price %d: total = %d, want 1", tt.price, total)
+			}
+
+			w = s.do(http.MethodGet, path+"/prices", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("prices: status = %d, body %s", w.Code, w.Body.String())
+			}
+			var prices struct {
+				Data []models.SubscriptionPrice `json:"data"`
+			}
+			decode(t, w, &prices)
+			if len(prices.Data) != len(tt.prices) {
+				t.Fatalf("got %d prices, want %d: %s", len(prices.Data), len(tt.prices), w.Body.String())
+			}
+			for i, price := range prices.Data {
+				if !price.EffectiveFrom.Equal(tt.prices[i].EffectiveFrom) || price.Price != tt.prices[i].Price {
+					t.Errorf("price %d = %d from %v, want %d from %v", i, price.Price, price.EffectiveFrom, tt.prices[i].Price, tt.prices[i].EffectiveFrom)
+				}
+			}
+
+			w = s.do(http.MethodGet, "/api/v1/subscriptions/total-cost?start_date=01-2025&end_date=06-2025", "")
+			var cost struct {
+				TotalCost int64 `json:"total_cost"`
+			}
+			decode(t, w, &cost)
+			if cost.TotalCost != tt.total {
+				t.Errorf("total_cost = %d, want %d", cost.TotalCost, tt.total)
+			}
+		})
+	}
+}
+
+// monthStartNow возвращает первое число текущего месяца
+func monthStartNow() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ServiceHandler struct {
+	service *service.CatalogService
+}
+
+func NewServiceHandler(catalogService *service.CatalogService) *ServiceHandler {
+	return &ServiceHandler{service: catalogService}
+}
+
+// CreateService добавляет сервис в каталог
+// @Summary Добавить сервис в каталог
+// @Description Добавляет сервис с псевдонимами. Название и псевдонимы сравниваются без учета регистра и лишних пробелов
+// @Description и не должны совпадать с названиями других сервисов (409). Подписки с совпадающим названием связываются с сервисом.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param service body ServiceRequest true "Данные сервиса"
+// @Success 201 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /services [post]
+func (h *ServiceHandler) CreateService(c *gin.Context) {
+	var req ServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	svc, err := h.service.Create(c.Request.Context(), serviceInput(req))
+	if err != nil {
+		respondError(c, err, "failed to create service")
+		return
+	}
+
+	log.Printf("Created service with ID: %s", svc.ID)
+	c.JSON(http.StatusCreated, svc)
+}
+
+// ListServices возвращает каталог сервисов
+// @Summary Каталог сервисов
+// @Description Возвращает сервисы каталога, отсортированные по названию.
+// @Tags services
+// @Produce json
+// @Param search query string false "Подстрока названия или псевдонима без учета регистра"
+// @Param category query string false "Категория"
+// @Success 200 {array} models.Service
+// @Failure 400 {object} map[string]string
+// @Router /services [get]
+func (h *ServiceHandler) ListServices(c *gin.Context) {
+	var req ListServicesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	services, err := h.service.List(c.Request.Context(), service.ServiceListInput{
+		Search:   req.Search,
+		Category: req.Category,
+	})
+	if err != nil {
+		respondError(c, err, "failed to list services")
+		return
+	}
+
+	log.Printf("Listed %d services", len(services))
+	c.JSON(http.StatusOK, services)
+}
+
+// GetService получает сервис каталога по ID
+// @Summary Получить сервис
+// @Description Возвращает сервис каталога с псевдонимами.
+// @Tags services
+// @Produce json
+// @Param id path string true "ID сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /services/{id} [get]
+func (h *ServiceHandler) GetService(c *gin.Context) {
+	serviceID, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	svc, err := h.service.Get(c.Request.Context(), serviceID)
+	if err != nil {
+		respondError(c, err, "failed to get service")
+		return
+	}
+
+	log.Printf("Retrieved service: %s", serviceID)
+	c.JSON(http.StatusOK, svc)
+}
+
+// UpdateService заменяет данные сервиса каталога
+// @Summary Обновить сервис
+// @Description Полностью заменяет данные и псевдонимы сервиса. Подписки на сервис получают новое название,
+// @Description подписки с названием из новых псевдонимов связываются с сервисом.
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path string true "ID сервиса"
+// @Param service body ServiceRequest true "Данные сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /services/{id} [put]
+func (h *ServiceHandler) UpdateService(c *gin.Context) {
+	serviceID, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	var req ServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	svc, err := h.service.Update(c.Request.Context(), serviceID, serviceInput(req))
+	if err != nil {
+		respondError(c, err, "failed to update service")
+		return
+	}
+
+	log.Printf("Updated service: %s", serviceID)
+	c.JSON(http.StatusOK, svc)
+}
+
+// DeleteService удаляет сервис из каталога
+// @Summary Удалить сервис
+// @Description Удаляет сервис и его псевдонимы. Подписки на сервис сохраняют название, но теряют ссылку на каталог.
+// @Tags services
+// @Param id path string true "ID сервиса"
+// @Success 204 "No Content"
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /services/{id} [delete]
+func (h *ServiceHandler) DeleteService(c *gin.Context) {
+	serviceID, ok := parseServiceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), serviceID); err != nil {
+		respondError(c, err, "failed to delete service")
+		return
+	}
+
+	log.Printf("Deleted service: %s", serviceID)
+	c.Status(http.StatusNoContent)
+}
+
+// serviceInput переводит запрос в данные сервиса каталога
+func serviceInput(req ServiceRequest) service.ServiceInput {
+	return service.ServiceInput{
+		Name:         req.Name,
+		Aliases:      req.Aliases,
+		Category:     req.Category,
+		DefaultPrice: req.DefaultPrice,
+		VendorURL:    req.VendorURL,
+	}
+}
@@ -0,0 +1,92 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestCatalogChangesAreJournaled(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	sub := s.create(`{"service_name":"yandex  plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+
+	w := s.do(http.MethodPost, "/api/v1/services", `{"name":"Yandex Plus","aliases":["Яндекс Плюс"]}`, "X-Actor", "alice")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create service: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var svc models.Service
+	decode(t, w, &svc)
+	path := "/api/v1/services/" + svc.ID.String()
+
+	steps := []struct {
+		method string
+		body   string
+		status int
+	}{
+		{http.MethodPut, `{"name":"Yandex Plus Multi","aliases":["Яндекс Плюс"]}`, http.StatusOK},
+		{http.MethodDelete, "", http.StatusNoContent},
+	}
+	for _, step := range steps {
+		if w := s.do(step.method, path, step.body, "X-Actor", "alice"); w.Code != step.status {
+			t.Fatalf("%s %s: status = %d, want %d, body %s", step.method, path, w.Code, step.status, w.Body.String())
+		}
+	}
+
+	// Событие на каждое изменение подписки каталогом: связывание, переименование и отвязка
+	want := []struct {
+		field         string
+		before, after interface{}
+	}{
+		{"service_name", "yandex  plus", "Yandex Plus"},
+		{"service_name", "Yandex Plus", "Yandex Plus Multi"},
+		{"service_id", svc.ID.String(), nil},
+	}
+	events := history(t, s, sub.ID)
+	if len(events) != len(want)+1 {
+		t.Fatalf("got %d events, want created and %d catalog events", len(events), len(want))
+	}
+	for i, exp := range want {
+		event := events[i+1]
+		if event.Action != models.EventUpdated || event.Actor != "alice" {
+			t.Errorf("event %d = %s by %s, want %s by alice", i+1, event.Action, event.Actor, models.EventUpdated)
+		}
+		if change := event.Changes[exp.field]; change.Before != exp.before || change.After != exp.after {
+			t.Errorf("event %d %s change = %+v, want %v -> %v", i+1, exp.field, change, exp.before, exp.after)
+		}
+	}
+
+	w = s.do(http.MethodGet, "/api/v1/subscriptions/"+sub.ID.String(), "")
+	var got models.Subscription
+	decode(t, w, &got)
+	if got.Version != 4 || got.ServiceID != nil || got.ServiceName != "Yandex Plus Multi" {
+		t.Errorf("subscription = v%d %q (service %v), want v4 %q without service", got.Version, got.ServiceName, got.ServiceID, "Yandex Plus Multi")
+	}
+}
+
+func TestUpdateServiceKeepsCreatedAt(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	w := s.do(http.MethodPost, "/api/v1/services", `{"name":"Yandex Plus","aliases":["Яндекс Плюс"]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create service: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var created models.Service
+	decode(t, w, &created)
+
+	w = s.do(http.MethodPut, "/api/v1/services/"+created.ID.String(), `{"name":"Yandex Plus Multi","aliases":["Яндекс Плюс","Плюс"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update service: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var updated models.Service
+	decode(t, w, &updated)
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", updated.CreatedAt, created.CreatedAt)
+	}
+	if updated.UpdatedAt.Before(created.UpdatedAt) {
+		t.Errorf("updated_at = %v, want not before %v", updated.UpdatedAt, created.UpdatedAt)
+	}
+	if updated.Name != "Yandex Plus Multi" || len(updated.Aliases) != 2 || updated.Aliases[0] != "Плюс" {
+		t.Errorf("service = %q %v, want %q with sorted aliases", updated.Name, updated.Aliases, "Yandex Plus Multi")
+	}
+}
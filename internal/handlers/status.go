@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PauseSubscription приостанавливает подписку
+// @Summary Приостановить подписку
+// @Description Переводит действующую подписку (active) в статус paused с текущего месяца.
+// @Description Месяцы приостановки не учитываются в расчете стоимости.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param If-Match header string false "ETag версии подписки, которую изменяет клиент"
+// @Success 200 {object} models.Subscription
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /subscriptions/{id}/pause [post]
+func (h *SubscriptionHandler) PauseSubscription(c *gin.Context) {
+	h.transition(c, "paused", "failed to pause subscription", h.service.Pause)
+}
+
+// ResumeSubscription возобновляет подписку
+// @Summary Возобновить подписку
+// @Description Переводит приостановленную подписку (paused) в статус active с текущего месяца.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param If-Match header string false "ETag версии подписки, которую изменяет клиент"
+// @Success 200 {object} models.Subscription
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /subscriptions/{id}/resume [post]
+func (h *SubscriptionHandler) ResumeSubscription(c *gin.Context) {
+	h.transition(c, "resumed", "failed to resume subscription", h.service.Resume)
+}
+
+// CancelSubscription отменяет подписку
+// @Summary Отменить подписку
+// @Description Переводит действующую или приостановленную подписку в статус cancelled. Подписка действует
+// @Description до конца текущего периода оплаты: дата окончания переносится на его последний месяц, если не наступает раньше.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param If-Match header string false "ETag версии подписки, которую изменяет клиент"
+// @Success 200 {object} models.Subscription
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Router /subscriptions/{id}/cancel [post]
+func (h *SubscriptionHandler) CancelSubscription(c *gin.Context) {
+	h.transition(c, "cancelled", "failed to cancel subscription", h.service.Cancel)
+}
+
+// transition выполняет переход подписки в другой статус
+func (h *SubscriptionHandler) transition(c *gin.Context, done, fallback string,
+	apply func(ctx context.Context, id uuid.UUID, ifMatch service.VersionMatch) (*models.Subscription, error)) {
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := apply(c.Request.Context(), subscriptionID, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err, fallback)
+		return
+	}
+
+	log.Printf("Subscription %s: %s", done, subscriptionID)
+	setETag(c, subscription)
+	c.JSON(http.StatusOK, subscription)
+}
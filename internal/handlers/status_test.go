@@ -0,0 +1,246 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestStatusTransitions(t *testing.T) {
+	const (
+		active = `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`
+		trial  = `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2099","trial_months":1}`
+		ended  = `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025","end_date":"06-2025"}`
+	)
+
+	// step - переход и ожидаемый результат. status пуст, если переход отклоняется
+	type step struct {
+		action string
+		code   int
+		status string
+	}
+	tests := []struct {
+		name    string
+		body    string
+		initial string
+		steps   []step
+	}{
+		{
+			name:    "pause and resume",
+			body:    active,
+			initial: models.StatusActive,
+			steps: []step{
+				{"pause", http.StatusOK, models.StatusPaused},
+				{"pause", http.StatusConflict, ""},
+				{"resume", http.StatusOK, models.StatusActive},
+				{"resume", http.StatusConflict, ""},
+			},
+		},
+		{
+			name:    "cancel active",
+			body:    active,
+			initial: models.StatusActive,
+			steps: []step{
+				{"cancel", http.StatusOK, models.StatusCancelled},
+				{"cancel", http.StatusConflict, ""},
+				{"pause", http.StatusConflict, ""},
+				{"resume", http.StatusConflict, ""},
+			},
+		},
+		{
+			name:    "cancel paused",
+			body:    active,
+			initial: models.StatusActive,
+			steps: []step{
+				{"pause", http.StatusOK, models.StatusPaused},
+				{"cancel", http.StatusOK, models.StatusCancelled},
+			},
+		},
+		{
+			name:    "trial",
+			body:    trial,
+			initial: models.StatusTrial,
+			steps: []step{
+				{"pause", http.StatusConflict, ""},
+				{"resume", http.StatusConflict, ""},
+				{"cancel", http.StatusOK, models.StatusCancelled},
+			},
+		},
+		{
+			name:    "expired",
+			body:    ended,
+			initial: models.StatusExpired,
+			steps: []step{
+				{"pause", http.StatusConflict, ""},
+				{"resume", http.StatusConflict, ""},
+				{"cancel", http.StatusConflict, ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(tt.body)
+			if sub.Status != tt.initial {
+				t.Fatalf("initial status = %q, want %q", sub.Status, tt.initial)
+			}
+
+			version := sub.Version
+			for i, st := range tt.steps {
+				w := s.do(http.MethodPost, "/api/v1/subscriptions/"+sub.ID.String()+"/"+st.action, "")
+				if w.Code != st.code {
+					t.Fatalf("step %d %s: status = %d, want %d, body %s", i, st.action, w.Code, st.code, w.Body.String())
+				}
+				if w.Code != http.StatusOK {
+					continue
+				}
+				var got models.Subscription
+				decode(t, w, &got)
+				if got.Status != st.status || got.Version != version+1 {
+					t.Errorf("step %d %s: status = %q, version = %d, want %q, %d", i, st.action, got.Status, got.Version, st.status, version+1)
+				}
+				version = got.Version
+			}
+		})
+	}
+}
+
+func TestCancelEndsWithBillingPeriod(t *testing.T) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		body string
+		want time.Time
+	}{
+		{
+			name: "monthly ends this month",
+			body: `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`,
+			want: month,
+		},
+		{
+			name: "earlier end date is kept",
+			body: `{"service_name":"Yandex Plus","price":400,"billing_period":"annual","user_id":"` + testUserID +
+				`","start_date":"` + month.Format("01-2006") + `","end_date":"` + month.AddDate(0, 2, 0).Format("01-2006") + `"}`,
+			want: month.AddDate(0, 2, 0),
+		},
+		{
+			name: "annual ends with the paid year",
+			body: `{"service_name":"Yandex Plus","price":4000,"billing_period":"annual","user_id":"` + testUserID +
+				`","start_date":"` + month.Format("01-2006") + `"}`,
+			want: month.AddDate(0, 11, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(tt.body)
+
+			w := s.do(http.MethodPost, "/api/v1/subscriptions/"+sub.ID.String()+"/cancel", "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got models.Subscription
+			decode(t, w, &got)
+			if got.EndDate == nil || !got.EndDate.Equal(tt.want) {
+				t.Errorf("end_date = %v, want %v", got.EndDate, tt.want)
+			}
+			if got.CancelledAt == nil {
+				t.Error("cancelled_at is not set")
+			}
+		})
+	}
+}
+
+func TestTransitionHistory(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	path := "/api/v1/subscriptions/" + sub.ID.String()
+	for _, action := range []string{"pause", "resume", "cancel"} {
+		if w := s.do(http.MethodPost, path+"/"+action, "", "X-Actor", "alice"); w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d, body %s", action, w.Code, w.Body.String())
+		}
+	}
+
+	events := history(t, s, sub.ID)
+	want := []string{models.EventCreated, models.EventPaused, models.EventResumed, models.EventCancelled}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %v", len(events), want)
+	}
+	for i, event := range events {
+		if event.Action != want[i] {
+			t.Errorf("event %d action = %q, want %q", i, event.Action, want[i])
+		}
+		if i > 0 && event.Actor != "alice" {
+			t.Errorf("event %d actor = %q, want alice", i, event.Actor)
+		}
+	}
+	if change := events[1].Changes["status"]; change.Before != models.StatusActive || change.After != models.StatusPaused {
+		t.Errorf("paused event status change = %+v, want active -> paused", change)
+	}
+}
+
+func TestExpireEndedIsJournaled(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	ctx := context.Background()
+
+	// Подписка, закончившаяся после последнего запуска фоновой задачи
+	end := time.Date(2025, time.June, 1, 0, 0, 0, 0, time.UTC)
+	sub := &models.Subscription{
+		ServiceName:   "Yandex Plus",
+		Price:         400,
+		Currency:      "RUB",
+		BillingPeriod: models.BillingPeriodMonthly,
+		UserID:        uuid.MustParse(testUserID),
+		StartDate:     time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:       &end,
+		Status:        models.StatusActive,
+	}
+	if err := s.repo.Create(ctx, sub); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	expired, err := s.service.ExpireEnded(ctx)
+	if err != nil || expired != 1 {
+		t.Fatalf("ExpireEnded() = %d, %v, want 1", expired, err)
+	}
+	if expired, _ := s.service.ExpireEnded(ctx); expired != 0 {
+		t.Errorf("second ExpireEnded() = %d, want 0", expired)
+	}
+
+	events := history(t, s, sub.ID)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Action != models.EventExpired || event.Actor != repository.SystemActor {
+		t.Errorf("event = %s by %s, want %s by %s", event.Action, event.Actor, models.EventExpired, repository.SystemActor)
+	}
+	if change := event.Changes["status"]; change.Before != models.StatusActive || change.After != models.StatusExpired {
+		t.Errorf("status change = %+v, want active -> expired", change)
+	}
+}
+
+// history возвращает журнал изменений подписки
+func history(t *testing.T, s *testServer, id uuid.UUID) []models.SubscriptionEvent {
+	t.Helper()
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/"+id.String()+"/history", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []models.SubscriptionEvent `json:"data"`
+	}
+	decode(t, w, &body)
+	return body.Data
+}
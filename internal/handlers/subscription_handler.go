@@ -1,57 +1,41 @@
 package handlers
 
 import (
+	"errors"
+	"io"
 	"log"
 	"net/http"
-	"strconv"
-	"strings"
-	"time"
 
 	"subscription-service/internal/models"
+	"subscription-service/internal/service"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
 type SubscriptionHandler struct {
-	db *gorm.DB
+	service *service.SubscriptionService
 }
 
-func NewSubscriptionHandler(db *gorm.DB) *SubscriptionHandler {
-	return &SubscriptionHandler{db: db}
-}
-
-
-// parseMonthYear парсит строку формата "MM-YYYY" в time.Time
-func parseMonthYear(dateStr string) (time.Time, error) {
-	parts := strings.Split(dateStr, "-")
-	if len(parts) != 2 {
-		return time.Time{}, gin.Error{Err: nil, Type: gin.ErrorTypeBind, Meta: "invalid date format, expected MM-YYYY"}
-	}
-
-	month, err := strconv.Atoi(parts[0])
-	if err != nil || month < 1 || month > 12 {
-		return time.Time{}, gin.Error{Err: nil, Type: gin.ErrorTypeBind, Meta: "invalid month"}
-	}
-
-	year, err := strconv.Atoi(parts[1])
-	if err != nil {
-		return time.Time{}, gin.Error{Err: nil, Type: gin.ErrorTypeBind, Meta: "invalid year"}
-	}
-
-	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+func NewSubscriptionHandler(subscriptionService *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{service: subscriptionService}
 }
 
 // CreateSubscription создает новую подписку
 // @Summary Создать подписку
-// @Description Создает новую запись о подписке
+// @Description Создает новую запись о подписке.
+// @Description С заголовком Idempotency-Key ответ сохраняется на срок из конфигурации: повтор запроса с тем же ключом
+// @Description и теми же данными возвращает сохраненный ответ с заголовком Idempotent-Replayed: true, с другими данными - 422.
+// @Description Пересечение периода с другой подпиской пользователя на тот же сервис отклоняется (409) или,
+// @Description если пересечения разрешены с предупреждением, возвращается в заголовке Warning.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body CreateSubscriptionRequest true "Данные подписки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности (до 255 символов)"
 // @Success 201 {object} models.Subscription
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /subscriptions [post]
 func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
@@ -62,258 +46,741 @@ func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
+	in := service.CreateInput{
+		ServiceName:   req.ServiceName,
+		Price:         req.Price,
+		Currency:      req.Currency,
+		BillingPeriod: req.BillingPeriod,
+		BillingDay:    req.BillingDay,
+		UserID:        req.UserID,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		TrialMonths:   req.TrialMonths,
+		IntroPrice:    req.IntroPrice,
+		IntroMonths:   req.IntroMonths,
+	}
+
+	var subscription *models.Subscription
+	var err error
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		var replayed bool
+		subscription, replayed, err = h.service.CreateIdempotent(c.Request.Context(), key, in)
+		if err == nil && replayed {
+			log.Printf("Replayed subscription creation with idempotency key %q: %s", key, subscription.ID)
+			c.Header("Idempotent-Replayed", "true")
+			setETag(c, subscription)
+			c.JSON(http.StatusCreated, subscription)
+			return
+		}
+	} else {
+		subscription, err = h.service.Create(c.Request.Context(), in)
+	}
 	if err != nil {
-		log.Printf("Error parsing user_id: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format"})
+		respondError(c, err, "failed to create subscription")
+		return
+	}
+
+	log.Printf("Created subscription with ID: %s", subscription.ID)
+	setETag(c, subscription)
+	setOverlapWarning(c, subscription)
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// BatchSubscriptions выполняет пакет операций над подписками
+// @Summary Пакетные операции
+// @Description Выполняет до 1000 операций create, update (JSON Merge Patch) и delete в одной транзакции в порядке следования.
+// @Description С atomic=true ошибка любой операции отменяет весь пакет (остальные операции получают статус 424),
+// @Description иначе отменяется только ошибочная операция. Для каждой операции возвращается HTTP-статус и результат или ошибка.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param batch body BatchRequest true "Операции"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/batch [post]
+func (h *SubscriptionHandler) BatchSubscriptions(c *gin.Context) {
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	startDate, err := parseMonthYear(req.StartDate)
+	ops := make([]service.BatchOperation, len(req.Operations))
+	for i, op := range req.Operations {
+		ops[i] = batchOperation(op)
+	}
+
+	result, err := h.service.Batch(c.Request.Context(), ops, req.Atomic)
 	if err != nil {
-		log.Printf("Error parsing start_date: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected MM-YYYY"})
+		respondError(c, err, "failed to apply batch")
 		return
 	}
 
-	subscription := models.Subscription{
-		ServiceName: req.ServiceName,
-		Price:        req.Price,
-		UserID:       userID,
-		StartDate:    startDate,
+	items := make([]BatchItemResponse, len(result.Items))
+	failed := 0
+	for i, item := range result.Items {
+		items[i] = batchItemResponse(i, item)
+		if item.Err != nil || item.Aborted {
+			failed++
+		}
+	}
+
+	log.Printf("Applied batch: atomic=%t, committed=%t, operations=%d, failed=%d", req.Atomic, result.Committed, len(items), failed)
+	c.JSON(http.StatusOK, gin.H{
+		"atomic":    result.Atomic,
+		"committed": result.Committed,
+		"results":   items,
+	})
+}
+
+// ImportSubscriptions импортирует подписки из CSV
+// @Summary Импорт подписок из CSV
+// @Description Принимает CSV-файл в поле file формы multipart/form-data. Первая строка - заголовок с колонками
+// @Description service_name, price, user_id, start_date (обязательные), currency, billing_period, end_date (необязательные) в любом порядке.
+// @Description Строки проверяются по тем же правилам, что и при создании подписки, и сохраняются пачками;
+// @Description ошибочные строки пропускаются и попадают в отчет. С dry_run=true файл только проверяется.
+// @Description Каждая пачка проверяется отдельно, поэтому dry_run не находит пересечений между строками разных пачек.
+// @Description Если импорт прервался из-за ошибки чтения файла или сохранения, пачки, сохраненные до неё, остаются:
+// @Description ответ с ошибкой содержит отчет о них и stopped_at_row - первую необработанную строку файла.
+// @Tags subscriptions
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV-файл"
+// @Param dry_run query bool false "Только проверить файл, ничего не сохраняя"
+// @Param delimiter query string false "Разделитель колонок" default(,)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/import [post]
+func (h *SubscriptionHandler) ImportSubscriptions(c *gin.Context) {
+	var req ImportSubscriptionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	delimiter, ok := importDelimiter(req.Delimiter)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delimiter must be a single character other than a quote or a line break"})
+		return
 	}
 
-	if req.EndDate != "" {
-		endDate, err := parseMonthYear(req.EndDate)
+	// Файл читается из потока без сохранения целиком в памяти или на диске
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		log.Printf("Error reading multipart form: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected multipart/form-data with a CSV file in field \"file\""})
+		return
+	}
+
+	var report *importReport
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			log.Printf("Error parsing end_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected MM-YYYY"})
+			log.Printf("Error reading multipart form: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		subscription.EndDate = &endDate
-	}
+		if part.FormName() != "file" {
+			continue
+		}
 
-	if err := h.db.Create(&subscription).Error; err != nil {
-		log.Printf("Error creating subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create subscription"})
+		report, err = h.importCSV(c.Request.Context(), part, delimiter, req.DryRun)
+		if err != nil && report == nil {
+			respondError(c, err, "failed to import subscriptions")
+			return
+		}
+		if err != nil {
+			log.Printf("Import stopped at row %d: rows=%d, imported=%d, failed=%d", report.StoppedAt, report.Rows, report.Imported, len(report.Errors))
+			status, body := errorResponse(err, "failed to import subscriptions")
+			response := importResponse(req.DryRun, report)
+			response["error"] = body.Error
+			if body.Fields != nil {
+				response["fields"] = body.Fields
+			}
+			response["stopped_at_row"] = report.StoppedAt
+			c.JSON(status, response)
+			return
+		}
+		break
+	}
+	if report == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing CSV file in field \"file\""})
 		return
 	}
 
-	log.Printf("Created subscription with ID: %s", subscription.ID)
-	c.JSON(http.StatusCreated, subscription)
+	log.Printf("Imported subscriptions: dry_run=%t, rows=%d, imported=%d, failed=%d", req.DryRun, report.Rows, report.Imported, len(report.Errors))
+	c.JSON(http.StatusOK, importResponse(req.DryRun, report))
 }
 
 // GetSubscription получает подписку по ID
 // @Summary Получить подписку
-// @Description Возвращает подписку по её ID
+// @Description Возвращает подписку по её ID. Версия подписки и дата следующего списания возвращаются в заголовке ETag;
+// @Description если он совпадает с If-None-Match, возвращается 304 без тела.
 // @Tags subscriptions
 // @Produce json
 // @Param id path string true "ID подписки"
+// @Param If-None-Match header string false "ETag ранее полученной версии"
 // @Success 200 {object} models.Subscription
+// @Success 304 "Not Modified"
 // @Failure 404 {object} map[string]string
 // @Router /subscriptions/{id} [get]
 func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
-	id := c.Param("id")
-	subscriptionID, err := uuid.Parse(id)
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.Get(c.Request.Context(), subscriptionID)
 	if err != nil {
-		log.Printf("Error parsing subscription ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription ID format"})
+		respondError(c, err, "failed to get subscription")
 		return
 	}
 
-	var subscription models.Subscription
-	if err := h.db.Where("id = ?", subscriptionID).First(&subscription).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("Subscription not found: %s", id)
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-			return
-		}
-		log.Printf("Error getting subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get subscription"})
+	setETag(c, subscription)
+	if notModified(c, subscription) {
+		log.Printf("Subscription not modified: %s", subscriptionID)
+		c.Status(http.StatusNotModified)
 		return
 	}
 
-	log.Printf("Retrieved subscription: %s", id)
+	log.Printf("Retrieved subscription: %s", subscriptionID)
 	c.JSON(http.StatusOK, subscription)
 }
 
-// UpdateSubscription обновляет подписку
-// @Summary Обновить подписку
-// @Description Обновляет существующую подписку
+// UpdateSubscription полностью заменяет подписку
+// @Summary Заменить подписку
+// @Description Полностью заменяет данные подписки. Обязательны все поля, необходимые при создании;
+// @Description отсутствующие необязательные поля сбрасываются (currency и billing_period - в значения по умолчанию, end_date - очищается).
+// @Description Новая цена действует с месяца effective_from (по умолчанию текущего), прошлые месяцы считаются по прежней цене.
+// @Description Поле price сразу принимает новое значение, даже если effective_from - будущий месяц.
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param id path string true "ID подписки"
-// @Param subscription body UpdateSubscriptionRequest true "Данные для обновления"
+// @Param If-Match header string false "ETag версии подписки, которую заменяет клиент"
+// @Param subscription body ReplaceSubscriptionRequest true "Новые данные подписки"
 // @Success 200 {object} models.Subscription
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Router /subscriptions/{id} [put]
 func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
-	id := c.Param("id")
-	subscriptionID, err := uuid.Parse(id)
-	if err != nil {
-		log.Printf("Error parsing subscription ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription ID format"})
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
 		return
 	}
 
-	var req UpdateSubscriptionRequest
+	var req ReplaceSubscriptionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("Error binding JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var subscription models.Subscription
-	if err := h.db.Where("id = ?", subscriptionID).First(&subscription).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("Subscription not found: %s", id)
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
-			return
-		}
-		log.Printf("Error getting subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get subscription"})
+	subscription, err := h.service.Replace(c.Request.Context(), subscriptionID, service.CreateInput{
+		ServiceName:   req.ServiceName,
+		Price:         *req.Price,
+		Currency:      req.Currency,
+		BillingPeriod: req.BillingPeriod,
+		BillingDay:    req.BillingDay,
+		UserID:        req.UserID,
+		StartDate:     req.StartDate,
+		EndDate:       req.EndDate,
+		TrialMonths:   req.TrialMonths,
+		IntroPrice:    req.IntroPrice,
+		IntroMonths:   req.IntroMonths,
+	}, req.EffectiveFrom, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err, "failed to update subscription")
 		return
 	}
 
-	// Обновление полей
-	if req.ServiceName != "" {
-		subscription.ServiceName = req.ServiceName
-	}
-	if req.Price != nil {
-		subscription.Price = *req.Price
-	}
-	if req.UserID != "" {
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			log.Printf("Error parsing user_id: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format"})
-			return
-		}
-		subscription.UserID = userID
-	}
-	if req.StartDate != "" {
-		startDate, err := parseMonthYear(req.StartDate)
-		if err != nil {
-			log.Printf("Error parsing start_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected MM-YYYY"})
-			return
-		}
-		subscription.StartDate = startDate
+	log.Printf("Replaced subscription: %s", subscriptionID)
+	setETag(c, subscription)
+	setOverlapWarning(c, subscription)
+	c.JSON(http.StatusOK, subscription)
+}
+
+// PatchSubscription частично обновляет подписку
+// @Summary Частично обновить подписку
+// @Description Применяет к подписке JSON Merge Patch (RFC 7396): отсутствующие поля не изменяются,
+// @Description null очищает end_date и сбрасывает currency и billing_period в значения по умолчанию.
+// @Description Обязательные поля (service_name, price, user_id, start_date) нельзя установить в null.
+// @Description Новая цена действует с месяца effective_from (по умолчанию текущего), прошлые месяцы считаются по прежней цене.
+// @Description Поле price сразу принимает новое значение, даже если effective_from - будущий месяц.
+// @Tags subscriptions
+// @Accept application/merge-patch+json,json
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Param If-Match header string false "ETag версии подписки, которую изменяет клиент"
+// @Param subscription body PatchSubscriptionRequest true "Изменяемые поля"
+// @Success 200 {object} models.Subscription
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
+// @Failure 415 {object} map[string]string
+// @Router /subscriptions/{id} [patch]
+func (h *SubscriptionHandler) PatchSubscription(c *gin.Context) {
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
 	}
-	if req.EndDate != "" {
-		endDate, err := parseMonthYear(req.EndDate)
-		if err != nil {
-			log.Printf("Error parsing end_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected MM-YYYY"})
-			return
+
+	patch, err := parseMergePatch(c)
+	if err != nil {
+		log.Printf("Error parsing merge patch: %v", err)
+		status := http.StatusBadRequest
+		if errors.Is(err, errUnsupportedContentType) {
+			status = http.StatusUnsupportedMediaType
 		}
-		subscription.EndDate = &endDate
-	} else if req.EndDate == "" && subscription.EndDate != nil {
-		// Если передана пустая строка, удаляем end_date
-		subscription.EndDate = nil
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
 	}
 
-	if err := h.db.Save(&subscription).Error; err != nil {
-		log.Printf("Error updating subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update subscription"})
+	subscription, err := h.service.Patch(c.Request.Context(), subscriptionID, patch, parseIfMatch(c))
+	if err != nil {
+		respondError(c, err, "failed to update subscription")
 		return
 	}
 
-	log.Printf("Updated subscription: %s", id)
+	log.Printf("Patched subscription: %s", subscriptionID)
+	setETag(c, subscription)
+	setOverlapWarning(c, subscription)
 	c.JSON(http.StatusOK, subscription)
 }
 
 // DeleteSubscription удаляет подписку
 // @Summary Удалить подписку
-// @Description Удаляет подписку по её ID
+// @Description Перемещает подписку в корзину, откуда её можно восстановить.
+// @Description С hard=true подписка (в том числе уже находящаяся в корзине) удаляется безвозвратно - административная операция.
 // @Tags subscriptions
 // @Param id path string true "ID подписки"
+// @Param hard query bool false "Удалить безвозвратно"
+// @Param If-Match header string false "ETag версии подписки, которую удаляет клиент"
 // @Success 204 "No Content"
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 412 {object} map[string]string
 // @Router /subscriptions/{id} [delete]
 func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
-	id := c.Param("id")
-	subscriptionID, err := uuid.Parse(id)
-	if err != nil {
-		log.Printf("Error parsing subscription ID: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid subscription ID format"})
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
 		return
 	}
 
-	var subscription models.Subscription
-	if err := h.db.Where("id = ?", subscriptionID).First(&subscription).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			log.Printf("Subscription not found: %s", id)
-			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+	var req DeleteSubscriptionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Hard {
+		if err := h.service.HardDelete(c.Request.Context(), subscriptionID, parseIfMatch(c)); err != nil {
+			respondError(c, err, "failed to delete subscription")
 			return
 		}
-		log.Printf("Error getting subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get subscription"})
+		log.Printf("Permanently deleted subscription: %s", subscriptionID)
+		c.Status(http.StatusNoContent)
 		return
 	}
 
-	if err := h.db.Delete(&subscription).Error; err != nil {
-		log.Printf("Error deleting subscription: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete subscription"})
+	if err := h.service.Delete(c.Request.Context(), subscriptionID, parseIfMatch(c)); err != nil {
+		respondError(c, err, "failed to delete subscription")
 		return
 	}
 
-	log.Printf("Deleted subscription: %s", id)
+	log.Printf("Deleted subscription: %s", subscriptionID)
 	c.Status(http.StatusNoContent)
 }
 
 // ListSubscriptions возвращает список подписок
 // @Summary Список подписок
-// @Description Возвращает список всех подписок с пагинацией
+// @Description Возвращает список подписок с фильтрацией, сортировкой и пагинацией.
+// @Description В режиме pagination=offset используются page и limit, в режиме pagination=cursor - cursor и limit
+// @Description (обход по created_at и id, следующая страница запрашивается по next_cursor).
 // @Tags subscriptions
 // @Produce json
+// @Param pagination query string false "Режим пагинации: offset или cursor" default(offset)
 // @Param page query int false "Номер страницы" default(1)
 // @Param limit query int false "Количество записей на странице" default(10)
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param with_total query bool false "Считать общее количество записей (по умолчанию true для offset и false для cursor)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога"
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param currency query string false "Валюта (ISO-4217)"
+// @Param min_price query int false "Минимальная цена (по полю price - последней установленной цене)"
+// @Param max_price query int false "Максимальная цена"
+// @Param active_at query string false "Подписка активна в месяце (MM-YYYY)"
+// @Param has_end_date query bool false "Наличие даты окончания"
+// @Param status query string false "Статус: trial, active, paused, cancelled или expired"
+// @Param sort query string false "Сортировка field:asc|desc через запятую (service_name, price, start_date, end_date, created_at, updated_at)"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Router /subscriptions [get]
 func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset := (page - 1) * limit
+	h.listSubscriptions(c, false)
+}
 
-	var subscriptions []models.Subscription
-	var total int64
+// ListDeletedSubscriptions возвращает список удаленных подписок
+// @Summary Корзина подписок
+// @Description Возвращает удаленные подписки, которые ещё можно восстановить. Параметры совпадают со списком подписок.
+// @Tags subscriptions
+// @Produce json
+// @Param pagination query string false "Режим пагинации: offset или cursor" default(offset)
+// @Param page query int false "Номер страницы" default(1)
+// @Param limit query int false "Количество записей на странице" default(10)
+// @Param cursor query string false "Курсор следующей страницы (next_cursor из предыдущего ответа)"
+// @Param with_total query bool false "Считать общее количество записей (по умолчанию true для offset и false для cursor)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога"
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param sort query string false "Сортировка field:asc|desc через запятую"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/deleted [get]
+func (h *SubscriptionHandler) ListDeletedSubscriptions(c *gin.Context) {
+	h.listSubscriptions(c, true)
+}
 
-	if err := h.db.Model(&models.Subscription{}).Count(&total).Error; err != nil {
-		log.Printf("Error counting subscriptions: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count subscriptions"})
+// listSubscriptions отдает страницу действующих или удаленных подписок
+func (h *SubscriptionHandler) listSubscriptions(c *gin.Context, deleted bool) {
+	var req ListSubscriptionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.db.Offset(offset).Limit(limit).Find(&subscriptions).Error; err != nil {
-		log.Printf("Error listing subscriptions: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list subscriptions"})
+	result, err := h.service.List(c.Request.Context(), service.ListInput{
+		Pagination:  req.Pagination,
+		Page:        req.Page,
+		Limit:       req.Limit,
+		Cursor:      req.Cursor,
+		WithTotal:   req.WithTotal,
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		Search:      req.Search,
+		Currency:    req.Currency,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		ActiveAt:    req.ActiveAt,
+		HasEndDate:  req.HasEndDate,
+		Status:      req.Status,
+		Sort:        req.Sort,
+		Deleted:     deleted,
+	})
+	if err != nil {
+		respondError(c, err, "failed to list subscriptions")
 		return
 	}
 
-	log.Printf("Listed subscriptions: page=%d, limit=%d, total=%d", page, limit, total)
+	pagination := gin.H{"limit": result.Limit}
+	if result.UseCursor {
+		pagination["next_cursor"] = result.NextCursor
+	} else {
+		pagination["page"] = result.Page
+	}
+	if result.Total != nil {
+		pagination["total"] = *result.Total
+	}
+
+	var data interface{} = result.Items
+	if deleted {
+		items := make([]DeletedSubscriptionResponse, 0, len(result.Items))
+		for _, item := range result.Items {
+			items = append(items, DeletedSubscriptionResponse{Subscription: item, DeletedAt: item.DeletedAt.Time})
+		}
+		data = items
+	}
+
+	log.Printf("Listed subscriptions: deleted=%t, mode=%s, limit=%d, returned=%d", deleted, req.Pagination, result.Limit, len(result.Items))
 	c.JSON(http.StatusOK, gin.H{
-		"data": subscriptions,
-		"pagination": gin.H{
-			"page":  page,
-			"limit": limit,
-			"total": total,
-		},
+		"data":       data,
+		"pagination": pagination,
 	})
 }
 
+// ExportSubscriptions выгружает подписки потоком
+// @Summary Выгрузка подписок
+// @Description Выгружает все подписки, подходящие под фильтры списка, в CSV или JSON Lines без пагинации.
+// @Description Записи читаются из БД и отправляются клиенту потоком, не загружаясь в память целиком.
+// @Description В CSV даты подписки указаны в формате MM-YYYY, время создания и изменения - в RFC 3339.
+// @Tags subscriptions
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "Формат: csv или jsonl" default(csv)
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса или его псевдоним из каталога"
+// @Param search query string false "Подстрока названия сервиса без учета регистра"
+// @Param currency query string false "Валюта (ISO-4217)"
+// @Param min_price query int false "Минимальная цена (по полю price - последней установленной цене)"
+// @Param max_price query int false "Максимальная цена"
+// @Param active_at query string false "Подписка активна в месяце (MM-YYYY)"
+// @Param has_end_date query bool false "Наличие даты окончания"
+// @Param status query string false "Статус: trial, active, paused, cancelled или expired"
+// @Param sort query string false "Сортировка field:asc|desc через запятую" default(created_at:asc)
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/export [get]
+func (h *SubscriptionHandler) ExportSubscriptions(c *gin.Context) {
+	var req ExportSubscriptionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Ответ начинается с первой записью: до этого ошибку ещё можно вернуть обычным ответом
+	var encoder *exportEncoder
+	start := func() error {
+		if encoder != nil {
+			return nil
+		}
+		var err error
+		encoder, err = newExportEncoder(c, req.Format, "subscriptions", subscriptionExportHeader)
+		return err
+	}
+
+	count := 0
+	err := h.service.Export(c.Request.Context(), service.ExportInput{
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		Search:      req.Search,
+		Currency:    req.Currency,
+		MinPrice:    req.MinPrice,
+		MaxPrice:    req.MaxPrice,
+		ActiveAt:    req.ActiveAt,
+		HasEndDate:  req.HasEndDate,
+		Status:      req.Status,
+		Sort:        req.Sort,
+	}, func(sub *models.Subscription) error {
+		if err := start(); err != nil {
+			return err
+		}
+		count++
+		return encoder.Encode(sub, subscriptionExportRow(sub))
+	})
+	if err != nil && encoder == nil {
+		respondError(c, err, "failed to export subscriptions")
+		return
+	}
+	if err == nil {
+		err = start()
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		// Часть данных уже отправлена, статус ответа изменить нельзя
+		log.Printf("Error exporting subscriptions after %d rows: %v", count, err)
+		return
+	}
+
+	log.Printf("Exported subscriptions: format=%s, rows=%d", req.Format, count)
+}
+
+// RestoreSubscription восстанавливает удаленную подписку
+// @Summary Восстановить подписку
+// @Description Возвращает подписку из корзины. Версия подписки увеличивается.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Success 200 {object} models.Subscription
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /subscriptions/{id}/restore [post]
+func (h *SubscriptionHandler) RestoreSubscription(c *gin.Context) {
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
+	}
+
+	subscription, err := h.service.Restore(c.Request.Context(), subscriptionID)
+	if err != nil {
+		respondError(c, err, "failed to restore subscription")
+		return
+	}
+
+	log.Printf("Restored subscription: %s", subscriptionID)
+	setETag(c, subscription)
+	setOverlapWarning(c, subscription)
+	c.JSON(http.StatusOK, subscription)
+}
+
+// SubscriptionPrices возвращает шкалу цен подписки
+// @Summary Шкала цен подписки
+// @Description Возвращает цены подписки с месяцами, с которых они действуют, по возрастанию effective_from.
+// @Description Стоимость за каждый месяц рассчитывается по цене, действовавшей в этом месяце.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /subscriptions/{id}/prices [get]
+func (h *SubscriptionHandler) SubscriptionPrices(c *gin.Context) {
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
+	}
+
+	prices, err := h.service.Prices(c.Request.Context(), subscriptionID)
+	if err != nil {
+		respondError(c, err, "failed to get subscription prices")
+		return
+	}
+
+	log.Printf("Retrieved prices of subscription %s: %d entries", subscriptionID, len(prices))
+	c.JSON(http.StatusOK, gin.H{"data": prices})
+}
+
+// SubscriptionHistory возвращает журнал изменений подписки
+// @Summary История изменений подписки
+// @Description Возвращает события создания, изменения, удаления и восстановления подписки в порядке записи:
+// @Description действие, автор (X-Actor), ID запроса (X-Request-ID), время и изменившиеся поля со значениями до и после.
+// @Description История доступна и для удаленных подписок.
+// @Tags subscriptions
+// @Produce json
+// @Param id path string true "ID подписки"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /subscriptions/{id}/history [get]
+func (h *SubscriptionHandler) SubscriptionHistory(c *gin.Context) {
+	subscriptionID, ok := parseSubscriptionID(c)
+	if !ok {
+		return
+	}
+
+	events, err := h.service.History(c.Request.Context(), subscriptionID)
+	if err != nil {
+		respondError(c, err, "failed to get subscription history")
+		return
+	}
+
+	log.Printf("Retrieved history of subscription %s: %d events", subscriptionID, len(events))
+	c.JSON(http.StatusOK, gin.H{"data": events})
+}
+
+// SubscriptionOverlaps возвращает пересекающиеся подписки
+// @Summary Пересекающиеся подписки
+// @Description Возвращает пары действующих подписок одного пользователя на один сервис, периоды которых пересекаются,
+// @Description с первым и последним месяцем пересечения. Такие подписки учитываются в стоимости дважды.
+// @Tags subscriptions
+// @Produce json
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/overlaps [get]
+func (h *SubscriptionHandler) SubscriptionOverlaps(c *gin.Context) {
+	var req OverlapsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	overlaps, err := h.service.Overlaps(c.Request.Context(), req.UserID, req.ServiceName)
+	if err != nil {
+		respondError(c, err, "failed to find overlapping subscriptions")
+		return
+	}
+
+	log.Printf("Found %d overlapping subscription pairs", len(overlaps))
+	c.JSON(http.StatusOK, gin.H{"data": overlaps})
+}
+
+// EndingTrials возвращает пробные подписки, пробный период которых скоро закончится
+// @Summary Заканчивающиеся пробные периоды
+// @Description Возвращает подписки в статусе trial, пробный период которых заканчивается в ближайшие days дней,
+// @Description в порядке окончания. Для каждой подписки указан день начала оплаты и сумма первого списания
+// @Description в валюте подписки с учетом вводной цены.
+// @Tags subscriptions
+// @Produce json
+// @Param days query int false "Количество дней вперед (по умолчанию 7)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/trials [get]
+func (h *SubscriptionHandler) EndingTrials(c *gin.Context) {
+	var req EndingTrialsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	trials, err := h.service.EndingTrials(c.Request.Context(), req.UserID, req.Days)
+	if err != nil {
+		respondError(c, err, "failed to find ending trials")
+		return
+	}
+
+	log.Printf("Found %d trials ending in %d days", len(trials), req.Days)
+	c.JSON(http.StatusOK, gin.H{"data": trials})
+}
+
+// UpcomingCharges возвращает предстоящие списания
+// @Summary Предстоящие списания
+// @Description Возвращает списания по подпискам с сегодняшнего дня на days дней вперед в порядке дат.
+// @Description День списания задается billing_day подписки (по умолчанию 1, для коротких месяцев - последний день месяца).
+// @Description Суммы указаны в валюте подписки с учетом пробного периода, вводной цены и приостановок.
+// @Tags subscriptions
+// @Produce json
+// @Param days query int false "Количество дней вперед (по умолчанию 30)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/upcoming [get]
+func (h *SubscriptionHandler) UpcomingCharges(c *gin.Context) {
+	var req UpcomingChargesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	charges, err := h.service.UpcomingCharges(c.Request.Context(), req.UserID, req.Days)
+	if err != nil {
+		respondError(c, err, "failed to find upcoming charges")
+		return
+	}
+
+	log.Printf("Found %d charges in the next %d days", len(charges), req.Days)
+	c.JSON(http.StatusOK, gin.H{"data": charges})
+}
+
 // CalculateTotalCost рассчитывает суммарную стоимость подписок
 // @Summary Рассчитать стоимость подписок
-// @Description Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией
+// @Description Рассчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией.
+// @Description Стоимость подписки складывается из списаний за месяцы, в которые она активна внутри периода.
+// @Description Если период не указан, стоимость считается с начала подписки по текущий месяц.
+// @Description Цена подписки относится к её периоду оплаты (billing_period): без amortize она списывается целиком в месяц продления,
+// @Description с amortize=true распределяется равномерно по месяцам.
+// @Description total_cost приводится к валюте currency по курсам из конфигурации, totals содержит суммы в исходных валютах.
+// @Description С format=csv или jsonl вместо JSON выгружаются строки отчета: группы (суммы в валюте currency)
+// @Description или стоимость по подпискам (в валюте подписки).
 // @Tags subscriptions
 // @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
 // @Param start_date query string false "Начало периода (MM-YYYY)"
 // @Param end_date query string false "Конец периода (MM-YYYY)"
 // @Param user_id query string false "ID пользователя (UUID)"
 // @Param service_name query string false "Название сервиса"
+// @Param group_by query string false "Группировка через запятую: service_name, user_id, month"
+// @Param currency query string false "Валюта результата (ISO-4217), по умолчанию базовая"
+// @Param amortize query bool false "Распределять цену длинных периодов оплаты по месяцам"
+// @Param format query string false "Формат ответа: json, или выгрузка строк отчета в csv или jsonl" default(json)
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
 // @Router /subscriptions/total-cost [get]
 func (h *SubscriptionHandler) CalculateTotalCost(c *gin.Context) {
 	var req TotalCostRequest
@@ -323,80 +790,123 @@ func (h *SubscriptionHandler) CalculateTotalCost(c *gin.Context) {
 		return
 	}
 
-	query := h.db.Model(&models.Subscription{})
-
-	// Фильтр по user_id
-	if req.UserID != "" {
-		userID, err := uuid.Parse(req.UserID)
-		if err != nil {
-			log.Printf("Error parsing user_id: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id format"})
-			return
-		}
-		query = query.Where("user_id = ?", userID)
+	report, err := h.service.TotalCost(c.Request.Context(), service.TotalCostInput{
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		GroupBy:     req.GroupBy,
+		Currency:    req.Currency,
+		Amortize:    req.Amortize,
+	})
+	if err != nil {
+		respondError(c, err, "failed to calculate total cost")
+		return
 	}
 
-	// Фильтр по service_name
-	if req.ServiceName != "" {
-		query = query.Where("service_name = ?", req.ServiceName)
+	response := gin.H{
+		"total_cost": report.TotalCost,
+		"currency":   report.Currency,
+		"totals":     report.Totals,
+		"filters": gin.H{
+			"start_date":   req.StartDate,
+			"end_date":     req.EndDate,
+			"user_id":      req.UserID,
+			"service_name": req.ServiceName,
+			"group_by":     req.GroupBy,
+			"amortize":     req.Amortize,
+		},
+	}
+	if report.Groups != nil {
+		response["groups"] = report.Groups
+	} else {
+		response["breakdown"] = report.Breakdown
 	}
 
-	// Фильтр по периоду
-	if req.StartDate != "" && req.EndDate != "" {
-		// Если указаны оба периода, используем диапазон
-		startDate, err := parseMonthYear(req.StartDate)
-		if err != nil {
-			log.Printf("Error parsing start_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected MM-YYYY"})
-			return
-		}
-		endDate, err := parseMonthYear(req.EndDate)
-		if err != nil {
-			log.Printf("Error parsing end_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected MM-YYYY"})
-			return
-		}
+	log.Printf("Calculated total cost: %d %s", report.TotalCost, report.Currency)
+	if req.Format != "json" {
+		h.exportTotalCost(c, req.Format, report)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
 
-		startOfPeriod := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-		endOfPeriod := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-		endOfPeriod = endOfPeriod.AddDate(0, 1, -1)
+// exportTotalCost выгружает строки отчета о стоимости: группы, если задана группировка, иначе стоимость по подпискам
+func (h *SubscriptionHandler) exportTotalCost(c *gin.Context, format string, report *service.TotalCostReport) {
+	header := costBreakdownHeader
+	if report.Groups != nil {
+		header = costGroupHeader(report.GroupBy)
+	}
+	encoder, err := newExportEncoder(c, format, "total-cost", header)
+	if err != nil {
+		log.Printf("Error exporting total cost: %v", err)
+		return
+	}
 
-		// Подписка активна в периоде, если она началась до конца периода и не закончилась до начала периода
-		query = query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", endOfPeriod, startOfPeriod)
-	} else if req.StartDate != "" {
-		startDate, err := parseMonthYear(req.StartDate)
-		if err != nil {
-			log.Printf("Error parsing start_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected MM-YYYY"})
-			return
+	if report.Groups != nil {
+		for _, group := range report.Groups {
+			record := struct {
+				service.CostGroup
+				Currency string `json:"currency"`
+			}{group, report.Currency}
+			if err = encoder.Encode(record, costGroupRow(group, report.GroupBy, report.Currency)); err != nil {
+				break
+			}
 		}
-		startOfMonth := time.Date(startDate.Year(), startDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-		endOfMonth := startOfMonth.AddDate(0, 1, -1)
-
-		query = query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", endOfMonth, startOfMonth)
-	} else if req.EndDate != "" {
-		endDate, err := parseMonthYear(req.EndDate)
-		if err != nil {
-			log.Printf("Error parsing end_date: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected MM-YYYY"})
-			return
+	} else {
+		for _, item := range report.Breakdown {
+			if err = encoder.Encode(item, costBreakdownRow(item)); err != nil {
+				break
+			}
 		}
-		startOfMonth := time.Date(endDate.Year(), endDate.Month(), 1, 0, 0, 0, 0, time.UTC)
-		endOfMonth := startOfMonth.AddDate(0, 1, -1)
+	}
+	if err == nil {
+		err = encoder.Flush()
+	}
+	if err != nil {
+		log.Printf("Error exporting total cost: %v", err)
+	}
+}
 
-		query = query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", endOfMonth, startOfMonth)
+// CostSeries возвращает помесячный ряд расходов на подписки
+// @Summary Помесячная стоимость подписок
+// @Description Возвращает по одной записи на каждый месяц периода: суммарную стоимость и количество активных подписок
+// @Tags subscriptions
+// @Produce json
+// @Param start_date query string true "Начало периода (MM-YYYY)"
+// @Param end_date query string true "Конец периода (MM-YYYY)"
+// @Param user_id query string false "ID пользователя (UUID)"
+// @Param service_name query string false "Название сервиса"
+// @Param currency query string false "Валюта результата (ISO-4217), по умолчанию базовая"
+// @Param amortize query bool false "Распределять цену длинных периодов оплаты по месяцам"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /subscriptions/cost-series [get]
+func (h *SubscriptionHandler) CostSeries(c *gin.Context) {
+	var req CostSeriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	var totalCost int64
-	if err := query.Select("COALESCE(SUM(price), 0)").Scan(&totalCost).Error; err != nil {
-		log.Printf("Error calculating total cost: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to calculate total cost"})
+	report, err := h.service.CostSeries(c.Request.Context(), service.CostSeriesInput{
+		StartDate:   req.StartDate,
+		EndDate:     req.EndDate,
+		UserID:      req.UserID,
+		ServiceName: req.ServiceName,
+		Currency:    req.Currency,
+		Amortize:    req.Amortize,
+	})
+	if err != nil {
+		respondError(c, err, "failed to calculate cost series")
 		return
 	}
 
-	log.Printf("Calculated total cost: %d", totalCost)
+	log.Printf("Calculated cost series: %d months", len(report.Series))
 	c.JSON(http.StatusOK, gin.H{
-		"total_cost": totalCost,
+		"series":   report.Series,
+		"currency": report.Currency,
 		"filters": gin.H{
 			"start_date":   req.StartDate,
 			"end_date":     req.EndDate,
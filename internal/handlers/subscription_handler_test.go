@@ -0,0 +1,244 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestCreateSubscription(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		// field - поле, о котором сообщает ошибка валидации
+		field string
+	}{
+		{
+			name:   "valid",
+			body:   `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			status: http.StatusCreated,
+		},
+		{
+			name:   "with end date and currency",
+			body:   `{"service_name":"Netflix","price":10,"currency":"USD","user_id":"` + testUserID + `","start_date":"07-2025","end_date":"12-2030"}`,
+			status: http.StatusCreated,
+		},
+		{
+			name:   "malformed json",
+			body:   `{"service_name":`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing service name",
+			body:   `{"price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid user id",
+			body:   `{"service_name":"Yandex Plus","price":400,"user_id":"not-a-uuid","start_date":"07-2025"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid start date",
+			body:   `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"13-2025"}`,
+			status: http.StatusBadRequest,
+			field:  "start_date",
+		},
+		{
+			name:   "end date before start date",
+			body:   `{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025","end_date":"01-2025"}`,
+			status: http.StatusBadRequest,
+			field:  "end_date",
+		},
+		{
+			name:   "unsupported currency",
+			body:   `{"service_name":"Yandex Plus","price":400,"currency":"EUR","user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			status: http.StatusBadRequest,
+			field:  "currency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			w := s.do(http.MethodPost, "/api/v1/subscriptions", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if tt.field != "" {
+				if _, ok := errorFields(t, w)[tt.field]; !ok {
+					t.Errorf("error fields %s do not mention %q", w.Body.String(), tt.field)
+				}
+			}
+			if w.Code != http.StatusCreated {
+				return
+			}
+
+			var sub models.Subscription
+			decode(t, w, &sub)
+			if sub.Version != 1 || sub.Status != models.StatusActive {
+				t.Errorf("version = %d, status = %q, want 1, %q", sub.Version, sub.Status, models.StatusActive)
+			}
+			if sub.Currency == "" {
+				t.Error("currency is empty, want explicit currency")
+			}
+			if want := `"1-` + sub.NextChargeDate.Format("20060102") + `"`; w.Header().Get("ETag") != want {
+				t.Errorf("ETag = %q, want %q", w.Header().Get("ETag"), want)
+			}
+		})
+	}
+}
+
+func TestGetSubscription(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "existing", id: sub.ID.String(), status: http.StatusOK},
+		{name: "unknown", id: "2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a", status: http.StatusNotFound},
+		{name: "invalid id", id: "42", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/"+tt.id, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got models.Subscription
+			decode(t, w, &got)
+			if got.ID != sub.ID || got.ServiceName != "Yandex Plus" || got.Price != 400 {
+				t.Errorf("got %+v, want subscription %s", got, sub.ID)
+			}
+		})
+	}
+}
+
+func TestUpdateSubscription(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		unknown bool
+		status  int
+		price   int
+	}{
+		{
+			name:   "replace price",
+			body:   `{"service_name":"Yandex Plus","price":500,"user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			status: http.StatusOK,
+			price:  500,
+		},
+		{
+			name:   "missing required field",
+			body:   `{"service_name":"Yandex Plus","user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:    "unknown subscription",
+			body:    `{"service_name":"Yandex Plus","price":500,"user_id":"` + testUserID + `","start_date":"07-2025"}`,
+			unknown: true,
+			status:  http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+			id := sub.ID.String()
+			if tt.unknown {
+				id = "2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a"
+			}
+
+			w := s.do(http.MethodPut, "/api/v1/subscriptions/"+id, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got models.Subscription
+			decode(t, w, &got)
+			if got.Price != tt.price || got.Version != 2 {
+				t.Errorf("price = %d, version = %d, want %d, 2", got.Price, got.Version, tt.price)
+			}
+		})
+	}
+}
+
+func TestDeleteSubscription(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+	path := "/api/v1/subscriptions/" + sub.ID.String()
+
+	steps := []struct {
+		method string
+		status int
+	}{
+		{http.MethodDelete, http.StatusNoContent},
+		{http.MethodGet, http.StatusNotFound},
+		{http.MethodDelete, http.StatusNotFound},
+	}
+	for _, step := range steps {
+		if w := s.do(step.method, path, ""); w.Code != step.status {
+			t.Fatalf("%s %s: status = %d, want %d, body %s", step.method, path, w.Code, step.status, w.Body.String())
+		}
+	}
+}
+
+func TestListSubscriptions(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	const otherUserID = "0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b"
+	s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+	s.create(`{"service_name":"Netflix","price":10,"currency":"USD","user_id":"` + testUserID + `","start_date":"03-2025"}`)
+	s.create(`{"service_name":"Spotify","price":300,"user_id":"` + otherUserID + `","start_date":"01-2025"}`)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		services []string
+	}{
+		{name: "by user sorted by price", query: "?user_id=" + testUserID + "&sort=price:desc", status: http.StatusOK, services: []string{"Yandex Plus", "Netflix"}},
+		{name: "search", query: "?search=spot", status: http.StatusOK, services: []string{"Spotify"}},
+		{name: "currency", query: "?currency=USD", status: http.StatusOK, services: []string{"Netflix"}},
+		{name: "unknown sort field", query: "?sort=foo", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data       []models.Subscription `json:"data"`
+				Pagination struct {
+					Total int64 `json:"total"`
+				} `json:"pagination"`
+			}
+			decode(t, w, &body)
+			if len(body.Data) != len(tt.services) || body.Pagination.Total != int64(len(tt.services)) {
+				t.Fatalf("got %d items (total %d), want %v", len(body.Data), body.Pagination.Total, tt.services)
+			}
+			for i, sub := range body.Data {
+				if sub.ServiceName != tt.services[i] {
+					t.Errorf("item %d = %q, want %q", i, sub.ServiceName, tt.services[i])
+				}
+			}
+		})
+	}
+}
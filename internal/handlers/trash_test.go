@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"subscription-service/internal/handlers"
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// trash возвращает подписки из корзины
+func trash(t *testing.T, s *testServer) []handlers.DeletedSubscriptionResponse {
+	t.Helper()
+	w := s.do(http.MethodGet, "/api/v1/subscriptions/deleted", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("list deleted: status = %d, body %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []handlers.DeletedSubscriptionResponse `json:"data"`
+	}
+	decode(t, w, &body)
+	return body.Data
+}
+
+func TestSoftDelete(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	deleted := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+	kept := s.create(`{"service_name":"Netflix","price":800,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+
+	if w := s.do(http.MethodDelete, "/api/v1/subscriptions/"+deleted.ID.String(), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	if w := s.do(http.MethodGet, "/api/v1/subscriptions/"+deleted.ID.String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: status = %d, want 404", w.Code)
+	}
+	if total := s.total(""); total != 1 {
+		t.Errorf("list total = %d, want 1", total)
+	}
+	items := trash(t, s)
+	if len(items) != 1 || items[0].ID != deleted.ID || items[0].DeletedAt.IsZero() {
+		t.Fatalf("trash = %+v, want only %s with deleted_at", items, deleted.ID)
+	}
+	if events := history(t, s, deleted.ID); events[len(events)-1].Action != models.EventDeleted {
+		t.Errorf("last event = %q, want %q", events[len(events)-1].Action, models.EventDeleted)
+	}
+	if w := s.do(http.MethodGet, "/api/v1/subscriptions/"+kept.ID.String(), ""); w.Code != http.StatusOK {
+		t.Errorf("get kept: status = %d, want 200", w.Code)
+	}
+}
+
+func TestRestoreSubscription(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete bool
+		id     string
+		status int
+	}{
+		{name: "deleted", delete: true, status: http.StatusOK},
+		{name: "not deleted", status: http.StatusConflict},
+		{name: "unknown", id: "2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a", status: http.StatusNotFound},
+		{name: "invalid id", id: "42", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+			if tt.delete {
+				if w := s.do(http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+					t.Fatalf("delete: status = %d, body %s", w.Code, w.Body.String())
+				}
+			}
+			if tt.id != "" {
+				path = "/api/v1/subscriptions/" + tt.id
+			}
+
+			w := s.do(http.MethodPost, path+"/restore", "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var got models.Subscription
+			decode(t, w, &got)
+			if got.ID != sub.ID || got.Version != sub.Version+1 {
+				t.Errorf("restored %s v%d, want %s v%d", got.ID, got.Version, sub.ID, sub.Version+1)
+			}
+			if w := s.do(http.MethodGet, path, ""); w.Code != http.StatusOK {
+				t.Errorf("get restored: status = %d, want 200", w.Code)
+			}
+			if items := trash(t, s); len(items) != 0 {
+				t.Errorf("trash has %d subscriptions, want 0", len(items))
+			}
+			if events := history(t, s, sub.ID); events[len(events)-1].Action != models.EventRestored {
+				t.Errorf("last event = %q, want %q", events[len(events)-1].Action, models.EventRestored)
+			}
+		})
+	}
+}
+
+func TestHardDelete(t *testing.T) {
+	tests := []struct {
+		name    string
+		trashed bool
+		ifMatch string
+		status  int
+	}{
+		{name: "active", status: http.StatusNoContent},
+		{name: "from trash", trashed: true, status: http.StatusNoContent},
+		{name: "current version", ifMatch: `"1"`, status: http.StatusNoContent},
+		{name: "stale version", ifMatch: `"2"`, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+			if tt.trashed {
+				if w := s.do(http.MethodDelete, path, ""); w.Code != http.StatusNoContent {
+					t.Fatalf("delete: status = %d, body %s", w.Code, w.Body.String())
+				}
+			}
+
+			var headers []string
+			if tt.ifMatch != "" {
+				headers = []string{"If-Match", tt.ifMatch}
+			}
+			w := s.do(http.MethodDelete, path+"?hard=true", "", headers...)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusNoContent {
+				if w := s.do(http.MethodGet, path, ""); w.Code != http.StatusOK {
+					t.Errorf("get after failed delete: status = %d, want 200", w.Code)
+				}
+				return
+			}
+
+			// Подписка не попадает в корзину и не восстанавливается
+			if items := trash(t, s); len(items) != 0 {
+				t.Errorf("trash has %d subscriptions, want 0", len(items))
+			}
+			if w := s.do(http.MethodPost, path+"/restore", ""); w.Code != http.StatusNotFound {
+				t.Errorf("restore: status = %d, want 404", w.Code)
+			}
+			if w := s.do(http.MethodDelete, path+"?hard=true", ""); w.Code != http.StatusNotFound {
+				t.Errorf("second hard delete: status = %d, want 404", w.Code)
+			}
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	ctx := context.Background()
+	const retention = 30 * 24 * time.Hour
+
+	// Подписка в корзине дольше срока хранения
+	old := &models.Subscription{
+		ServiceName:   "Yandex Plus",
+		Price:         400,
+		Currency:      "RUB",
+		BillingPeriod: models.BillingPeriodMonthly,
+		UserID:        uuid.MustParse(testUserID),
+		StartDate:     time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		DeletedAt:     gorm.DeletedAt{Time: time.Now().UTC().Add(-retention - time.Hour), Valid: true},
+	}
+	if err := s.repo.Create(ctx, old); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	recent := s.create(`{"service_name":"Netflix","price":800,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+	if w := s.do(http.MethodDelete, "/api/v1/subscriptions/"+recent.ID.String(), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body %s", w.Code, w.Body.String())
+	}
+	s.create(`{"service_name":"Spotify","price":300,"user_id":"` + testUserID + `","start_date":"07-2025"}`)
+
+	purged, err := s.service.PurgeDeleted(ctx, retention)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeleted() = %d, %v, want 1", purged, err)
+	}
+	if items := trash(t, s); len(items) != 1 || items[0].ID != recent.ID {
+		t.Errorf("trash = %+v, want only %s", items, recent.ID)
+	}
+	if w := s.do(http.MethodPost, "/api/v1/subscriptions/"+old.ID.String()+"/restore", ""); w.Code != http.StatusNotFound {
+		t.Errorf("restore purged: status = %d, want 404", w.Code)
+	}
+	if total := s.total(""); total != 1 {
+		t.Errorf("list total = %d, want 1", total)
+	}
+}
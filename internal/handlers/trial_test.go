@@ -0,0 +1,182 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+	"subscription-service/internal/service"
+
+	"github.com/google/uuid"
+)
+
+func TestTrialAndIntroCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		extra    string
+		from, to string
+		want     int64
+	}{
+		{name: "no trial", from: "01-2025", to: "06-2025", want: 2400},
+		{name: "trial months are free", extra: `,"trial_months":2`, from: "01-2025", to: "06-2025", want: 1600},
+		{name: "intro price after trial", extra: `,"trial_months":1,"intro_price":100,"intro_months":2`, from: "01-2025", to: "06-2025", want: 1400},
+		{name: "intro price without trial", extra: `,"intro_price":100,"intro_months":3`, from: "01-2025", to: "04-2025", want: 700},
+		{name: "annual renews from trial end", extra: `,"billing_period":"annual","trial_months":1`, from: "01-2025", to: "01-2025", want: 0},
+		{name: "annual charged after trial", extra: `,"billing_period":"annual","trial_months":1`, from: "01-2025", to: "02-2025", want: 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"` + tt.extra + `}`)
+
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/total-cost?start_date="+tt.from+"&end_date="+tt.to, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var body struct {
+				TotalCost int64 `json:"total_cost"`
+			}
+			decode(t, w, &body)
+			if body.TotalCost != tt.want {
+				t.Errorf("total_cost = %d, want %d", body.TotalCost, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrialValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		field string
+	}{
+		{name: "intro price without months", extra: `,"intro_price":100`, field: "intro_months"},
+		{name: "intro months without price", extra: `,"intro_months":2`, field: "intro_price"},
+		{name: "negative trial", extra: `,"trial_months":-1`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			w := s.do(http.MethodPost, "/api/v1/subscriptions",
+				`{"service_name":"Yandex Plus","price":400,"user_id":"`+testUserID+`","start_date":"01-2025"`+tt.extra+`}`)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400, body %s", w.Code, w.Body.String())
+			}
+			if tt.field != "" {
+				if _, ok := errorFields(t, w)[tt.field]; !ok {
+					t.Errorf("error fields %s do not mention %q", w.Body.String(), tt.field)
+				}
+			}
+		})
+	}
+}
+
+func TestEndingTrials(t *testing.T) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	start := month.Format("01-2006")
+
+	s := newTestServer(t, service.Options{})
+	// Пробный период заканчивается в начале следующего месяца
+	soon := s.create(`{"service_name":"Yandex Plus","price":400,"billing_day":15,"user_id":"` + testUserID +
+		`","start_date":"` + start + `","trial_months":1,"intro_price":199,"intro_months":3}`)
+	// Пробный период заканчивается через три месяца
+	s.create(`{"service_name":"Netflix","price":800,"user_id":"` + testUserID + `","start_date":"` + start + `","trial_months":3}`)
+	s.create(`{"service_name":"Spotify","price":300,"user_id":"` + testUserID + `","start_date":"` + start + `"}`)
+	if soon.Status != models.StatusTrial {
+		t.Fatalf("status = %q, want %q", soon.Status, models.StatusTrial)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		services []string
+	}{
+		{name: "within days", query: "?days=45", status: http.StatusOK, services: []string{"Yandex Plus"}},
+		{name: "all trials", query: "?days=200", status: http.StatusOK, services: []string{"Yandex Plus", "Netflix"}},
+		{name: "other user", query: "?days=200&user_id=0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b", status: http.StatusOK},
+		{name: "invalid days", query: "?days=0", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/trials"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data []service.EndingTrial `json:"data"`
+			}
+			decode(t, w, &body)
+			if len(body.Data) != len(tt.services) {
+				t.Fatalf("got %d trials, want %v: %s", len(body.Data), tt.services, w.Body.String())
+			}
+			for i, trial := range body.Data {
+				if trial.Subscription.ServiceName != tt.services[i] {
+					t.Errorf("trial %d = %q, want %q", i, trial.Subscription.ServiceName, tt.services[i])
+				}
+			}
+			if len(body.Data) == 0 {
+				return
+			}
+
+			first := body.Data[0]
+			ends := month.AddDate(0, 1, 0)
+			if !first.TrialEndsAt.Equal(ends) || !first.FirstChargeDate.Equal(ends.AddDate(0, 0, 14)) {
+				t.Errorf("trial ends %v, first charge %v, want %v, %v", first.TrialEndsAt, first.FirstChargeDate, ends, ends.AddDate(0, 0, 14))
+			}
+			if first.FirstCharge != 199 || first.Currency != "RUB" {
+				t.Errorf("first charge = %d %s, want 199 RUB", first.FirstCharge, first.Currency)
+			}
+		})
+	}
+}
+
+func TestEndTrialsIsJournaled(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	ctx := context.Background()
+
+	// Пробный период закончился после последнего запуска фоновой задачи
+	sub := &models.Subscription{
+		ServiceName:   "Yandex Plus",
+		Price:         400,
+		Currency:      "RUB",
+		BillingPeriod: models.BillingPeriodMonthly,
+		UserID:        uuid.MustParse(testUserID),
+		StartDate:     time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		TrialMonths:   1,
+		Status:        models.StatusTrial,
+	}
+	if err := s.repo.Create(ctx, sub); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	ended, err := s.service.EndTrials(ctx)
+	if err != nil || ended != 1 {
+		t.Fatalf("EndTrials() = %d, %v, want 1", ended, err)
+	}
+	if ended, _ := s.service.EndTrials(ctx); ended != 0 {
+		t.Errorf("second EndTrials() = %d, want 0", ended)
+	}
+
+	events := history(t, s, sub.ID)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.Action != models.EventTrialEnded || event.Actor != repository.SystemActor {
+		t.Errorf("event = %s by %s, want %s by %s", event.Action, event.Actor, models.EventTrialEnded, repository.SystemActor)
+	}
+	if change := event.Changes["status"]; change.Before != models.StatusTrial || change.After != models.StatusActive {
+		t.Errorf("status change = %+v, want trial -> active", change)
+	}
+}
@@ -0,0 +1,119 @@
+package handlers_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/service"
+)
+
+func TestNextChargeDate(t *testing.T) {
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	tests := []struct {
+		name   string
+		extra  string
+		action string
+		want   *time.Time
+	}{
+		{name: "first day by default", extra: `,"start_date":"01-2099"`, want: date(2099, time.January, 1)},
+		{name: "billing day", extra: `,"start_date":"01-2099","billing_day":15`, want: date(2099, time.January, 15)},
+		{name: "billing day past month end", extra: `,"start_date":"02-2099","billing_day":31`, want: date(2099, time.February, 28)},
+		{name: "after trial", extra: `,"start_date":"01-2099","trial_months":2,"billing_day":10`, want: date(2099, time.March, 10)},
+		{name: "weekly", extra: `,"start_date":"01-2099","billing_period":"weekly","billing_day":3`, want: date(2099, time.January, 3)},
+		{name: "ended", extra: `,"start_date":"01-2025","end_date":"06-2025"`},
+		{name: "paused", extra: `,"start_date":"01-2025"`, action: "pause"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, service.Options{})
+			sub := s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `"` + tt.extra + `}`)
+			path := "/api/v1/subscriptions/" + sub.ID.String()
+			if tt.action != "" {
+				if w := s.do(http.MethodPost, path+"/"+tt.action, ""); w.Code != http.StatusOK {
+					t.Fatalf("%s: status = %d, body %s", tt.action, w.Code, w.Body.String())
+				}
+			}
+
+			w := s.do(http.MethodGet, path, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got models.Subscription
+			decode(t, w, &got)
+			if (got.NextChargeDate == nil) != (tt.want == nil) || (tt.want != nil && !got.NextChargeDate.Equal(*tt.want)) {
+				t.Errorf("next_charge_date = %v, want %v", got.NextChargeDate, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpcomingCharges(t *testing.T) {
+	const otherUserID = "0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b"
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	thisMonth := today.Format("01-2006")
+	billingDay := strconv.Itoa(today.Day())
+
+	s := newTestServer(t, service.Options{})
+	// Списания сегодня
+	s.create(`{"service_name":"Yandex Plus","price":400,"billing_day":` + billingDay + `,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	s.create(`{"service_name":"Kinopoisk","price":300,"billing_day":` + billingDay + `,"user_id":"` + testUserID +
+		`","start_date":"` + thisMonth + `","intro_price":99,"intro_months":24}`)
+	s.create(`{"service_name":"Spotify","price":250,"billing_day":` + billingDay + `,"user_id":"` + otherUserID + `","start_date":"01-2025"}`)
+	// Без списаний в ближайшие дни: приостановлена и на пробном периоде
+	paused := s.create(`{"service_name":"Netflix","price":800,"billing_day":` + billingDay + `,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+	if w := s.do(http.MethodPost, "/api/v1/subscriptions/"+paused.ID.String()+"/pause", ""); w.Code != http.StatusOK {
+		t.Fatalf("pause: status = %d, body %s", w.Code, w.Body.String())
+	}
+	s.create(`{"service_name":"Okko","price":500,"billing_day":15,"user_id":"` + testUserID + `","start_date":"` + thisMonth + `","trial_months":1}`)
+
+	// charge - ожидаемое списание сегодня
+	type charge struct {
+		service string
+		amount  int64
+	}
+	tests := []struct {
+		name    string
+		query   string
+		status  int
+		charges []charge
+	}{
+		{name: "all users", query: "?days=1", status: http.StatusOK, charges: []charge{{"Kinopoisk", 99}, {"Spotify", 250}, {"Yandex Plus", 400}}},
+		{name: "one user", query: "?days=1&user_id=" + testUserID, status: http.StatusOK, charges: []charge{{"Kinopoisk", 99}, {"Yandex Plus", 400}}},
+		{name: "invalid days", query: "?days=0", status: http.StatusBadRequest},
+		{name: "invalid user", query: "?days=1&user_id=42", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/subscriptions/upcoming"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+			var body struct {
+				Data []service.UpcomingCharge `json:"data"`
+			}
+			decode(t, w, &body)
+			if len(body.Data) != len(tt.charges) {
+				t.Fatalf("got %d charges, want %v: %s", len(body.Data), tt.charges, w.Body.String())
+			}
+			for i, got := range body.Data {
+				want := tt.charges[i]
+				if got.ServiceName != want.service || got.Amount != want.amount || !got.Date.Equal(today) {
+					t.Errorf("charge %d = %s %d on %v, want %s %d on %v", i, got.ServiceName, got.Amount, got.Date, want.service, want.amount, today)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"subscription-service/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UserHandler struct {
+	users         *service.UserService
+	subscriptions *service.SubscriptionService
+}
+
+func NewUserHandler(userService *service.UserService, subscriptionService *service.SubscriptionService) *UserHandler {
+	return &UserHandler{users: userService, subscriptions: subscriptionService}
+}
+
+// CreateUser создает пользователя
+// @Summary Создать пользователя
+// @Description Создает пользователя. Пользователи без имени и email создаются автоматически при первой подписке.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body CreateUserRequest true "Данные пользователя"
+// @Success 201 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.Create(c.Request.Context(), service.UserInput{ID: req.ID, Name: req.Name, Email: req.Email})
+	if err != nil {
+		respondError(c, err, "failed to create user")
+		return
+	}
+
+	log.Printf("Created user with ID: %s", user.ID)
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetUser получает пользователя по ID
+// @Summary Получить пользователя
+// @Tags users
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.users.Get(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "failed to get user")
+		return
+	}
+
+	log.Printf("Retrieved user: %s", userID)
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateUser заменяет данные пользователя
+// @Summary Обновить пользователя
+// @Description Заменяет имя и email пользователя.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Param user body UpdateUserRequest true "Данные пользователя"
+// @Success 200 {object} models.User
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.users.Update(c.Request.Context(), userID, service.UserInput{Name: req.Name, Email: req.Email})
+	if err != nil {
+		respondError(c, err, "failed to update user")
+		return
+	}
+
+	log.Printf("Updated user: %s", userID)
+	c.JSON(http.StatusOK, user)
+}
+
+// UserSummary возвращает сводку по подпискам пользователя
+// @Summary Сводка пользователя
+// @Description Возвращает подписки, действующие в текущем месяце, расходы за текущий месяц и с начала года,
+// @Description до 5 ближайших дат окончания подписок и самый дорогой сервис по стоимости в месяц
+// @Description (цена длинных периодов оплаты распределяется по месяцам).
+// @Tags users
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Param currency query string false "Валюта сумм (ISO-4217), по умолчанию базовая"
+// @Param amortize query bool false "Распределять цену длинных периодов оплаты по месяцам в расходах"
+// @Success 200 {object} service.UserSummary
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /users/{id}/summary [get]
+func (h *UserHandler) UserSummary(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UserSummaryRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("Error binding query: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	summary, err := h.subscriptions.UserSummary(c.Request.Context(), userID, service.UserSummaryInput{
+		Currency: req.Currency,
+		Amortize: req.Amortize,
+	})
+	if err != nil {
+		respondError(c, err, "failed to build user summary")
+		return
+	}
+
+	log.Printf("Built summary for user %s: %d active subscriptions", userID, len(summary.ActiveSubscriptions))
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"subscription-service/internal/service"
+)
+
+func TestUserSummary(t *testing.T) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	thisMonth := month.Format("01-2006")
+	// Месяцы с начала года по текущий включительно
+	monthsThisYear := int64(now.Month())
+
+	s := newTestServer(t, service.Options{})
+	// Действуют в текущем месяце
+	s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-` + month.Format("2006") + `"}`)
+	netflix := s.create(`{"service_name":"Netflix","price":10,"currency":"USD","user_id":"` + testUserID +
+		`","start_date":"` + thisMonth + `","end_date":"` + month.AddDate(0, 2, 0).Format("01-2006") + `"}`)
+	s.create(`{"service_name":"Spotify","price":1200,"billing_period":"annual","user_id":"` + testUserID + `","start_date":"` + thisMonth + `"}`)
+	// Не попадают в сводку: закончилась, удалена, принадлежит другому пользователю
+	s.create(`{"service_name":"Okko","price":500,"user_id":"` + testUserID + `","start_date":"01-2020","end_date":"12-2020"}`)
+	deleted := s.create(`{"service_name":"Ivi","price":300,"user_id":"` + testUserID + `","start_date":"` + thisMonth + `"}`)
+	if w := s.do(http.MethodDelete, "/api/v1/subscriptions/"+deleted.ID.String(), ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body %s", w.Code, w.Body.String())
+	}
+	s.create(`{"service_name":"Kinopoisk","price":300,"user_id":"0b4f2c8e-1d3a-4e5f-8a6b-7c9d0e1f2a3b","start_date":"` + thisMonth + `"}`)
+
+	tests := []struct {
+		name     string
+		query    string
+		currency string
+		monthly  int64
+		ytd      int64
+		top      service.ServiceSpend
+	}{
+		{
+			// Годовая подписка списывается целиком в месяц продления
+			name:     "charges",
+			currency: "RUB",
+			monthly:  400 + 900 + 1200,
+			ytd:      400*monthsThisYear + 900 + 1200,
+			top:      service.ServiceSpend{ServiceName: "Netflix", MonthlyCost: 900},
+		},
+		{
+			name:     "amortized",
+			query:    "?amortize=true",
+			currency: "RUB",
+			monthly:  400 + 900 + 100,
+			ytd:      400*monthsThisYear + 900 + 100,
+			top:      service.ServiceSpend{ServiceName: "Netflix", MonthlyCost: 900},
+		},
+		{
+			name:     "converted",
+			query:    "?currency=USD",
+			currency: "USD",
+			monthly:  4 + 10 + 13,
+			ytd:      4*monthsThisYear + 10 + 13,
+			top:      service.ServiceSpend{ServiceName: "Netflix", MonthlyCost: 10},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := s.do(http.MethodGet, "/api/v1/users/"+testUserID+"/summary"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+			}
+			var got service.UserSummary
+			decode(t, w, &got)
+
+			if got.UserID != testUserID || got.Month != thisMonth || got.Currency != tt.currency {
+				t.Errorf("summary for %s %s in %s, want %s %s in %s", got.UserID, got.Month, got.Currency, testUserID, thisMonth, tt.currency)
+			}
+			var active []string
+			for _, sub := range got.ActiveSubscriptions {
+				active = append(active, sub.ServiceName)
+			}
+			sort.Strings(active)
+			if want := []string{"Netflix", "Spotify", "Yandex Plus"}; !reflect.DeepEqual(active, want) {
+				t.Errorf("active subscriptions = %v, want %v", active, want)
+			}
+			if got.MonthlySpend != tt.monthly || got.YearToDateSpend != tt.ytd {
+				t.Errorf("monthly spend = %d, year to date = %d, want %d, %d", got.MonthlySpend, got.YearToDateSpend, tt.monthly, tt.ytd)
+			}
+			if got.MostExpensiveService == nil || *got.MostExpensiveService != tt.top {
+				t.Errorf("most expensive service = %+v, want %+v", got.MostExpensiveService, tt.top)
+			}
+			if len(got.UpcomingEndDates) != 1 || got.UpcomingEndDates[0].SubscriptionID != netflix.ID.String() ||
+				!got.UpcomingEndDates[0].EndDate.Equal(month.AddDate(0, 2, 0)) {
+				t.Errorf("upcoming end dates = %+v, want Netflix on %v", got.UpcomingEndDates, month.AddDate(0, 2, 0))
+			}
+		})
+	}
+}
+
+func TestUserSummaryErrors(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	s.create(`{"service_name":"Yandex Plus","price":400,"user_id":"` + testUserID + `","start_date":"01-2025"}`)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "unknown user", path: "/api/v1/users/2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a/summary", status: http.StatusNotFound},
+		{name: "invalid user id", path: "/api/v1/users/42/summary", status: http.StatusBadRequest},
+		{name: "unsupported currency", path: "/api/v1/users/" + testUserID + "/summary?currency=EUR", status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := s.do(http.MethodGet, tt.path, ""); w.Code != tt.status {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.status, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestUserSummaryWithoutSubscriptions(t *testing.T) {
+	s := newTestServer(t, service.Options{})
+	w := s.do(http.MethodPost, "/api/v1/users", `{"id":"`+testUserID+`","name":"Alice"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create user: status = %d, body %s", w.Code, w.Body.String())
+	}
+
+	w = s.do(http.MethodGet, "/api/v1/users/"+testUserID+"/summary", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body.String())
+	}
+	var got service.UserSummary
+	decode(t, w, &got)
+	if len(got.ActiveSubscriptions) != 0 || got.MonthlySpend != 0 || got.YearToDateSpend != 0 ||
+		len(got.UpcomingEndDates) != 0 || got.MostExpensiveService != nil {
+		t.Errorf("summary = %+v, want empty", got)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"subscription-service/internal/service"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// validationError переводит ошибки валидации структуры запроса req
+// в service.ValidationError с именами полей из тегов json
+func validationError(req interface{}, err error) *service.ValidationError {
+	verr := &service.ValidationError{}
+
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		verr.Add("body", err.Error())
+		return verr
+	}
+
+	t := reflect.TypeOf(req)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for _, fe := range fieldErrors {
+		name := fe.Field()
+		if field, ok := t.FieldByName(fe.StructField()); ok {
+			if tag := strings.Split(field.Tag.Get("json"), ",")[0]; tag != "" {
+				name = tag
+			}
+		}
+		verr.Add(name, validationMessage(fe))
+	}
+	return verr
+}
+
+// validationMessage описывает нарушенное правило валидации
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "uuid":
+		return "invalid format, expected UUID"
+	case "min":
+		return "must be at least " + fe.Param()
+	case "max":
+		return "must be at most " + fe.Param()
+	case "iso4217":
+		return "must be an ISO-4217 currency code"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	default:
+		return "failed on " + fe.Tag() + " validation"
+	}
+}
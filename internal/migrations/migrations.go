@@ -1,14 +1,20 @@
 package migrations
 
 import (
+	"context"
+	"fmt"
 	"log"
 
+	"subscription-service/internal/config"
 	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
 
 	"gorm.io/gorm"
 )
 
-func Run(db *gorm.DB) error {
+// Run выполняет миграции. baseCurrency - базовая валюта из конфигурации, которой заполняется
+// валюта подписок, созданных до появления мультивалютности
+func Run(db *gorm.DB, baseCurrency string, overlap config.OverlapConfig) error {
 	log.Println("Running migrations...")
 
 	// Создание расширения для UUID, если его нет
@@ -16,8 +22,27 @@ func Run(db *gorm.DB) error {
 		log.Printf("Note: uuid-ossp extension might already exist: %v", err)
 	}
 
+	// Валюта не имеет значения по умолчанию в схеме: базовая валюта задается конфигурацией,
+	// и сервис указывает валюту при создании подписки явно. Существующие подписки без валюты
+	// получают базовую валюту до того, как AutoMigrate сделает колонку NOT NULL
+	if db.Migrator().HasTable(&models.Subscription{}) {
+		currencyMigrations := []string{
+			"ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS currency varchar(3)",
+			"ALTER TABLE subscriptions ALTER COLUMN currency DROP DEFAULT",
+		}
+		for _, statement := range currencyMigrations {
+			if err := db.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if err := db.Exec("UPDATE subscriptions SET currency = ? WHERE currency IS NULL OR currency = ''", baseCurrency).Error; err != nil {
+			return err
+		}
+	}
+
 	// Автоматическая миграция схемы
-	if err := db.AutoMigrate(&models.Subscription{}); err != nil {
+	if err := db.AutoMigrate(&models.Subscription{}, &models.SubscriptionEvent{}, &models.SubscriptionPrice{}, &models.SubscriptionPause{}, &models.IdempotencyKey{},
+		&models.Service{}, &models.ServiceAlias{}, &models.User{}); err != nil {
 		return err
 	}
 
@@ -27,6 +52,9 @@ func Run(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_subscriptions_start_date ON subscriptions(start_date)",
 		"CREATE INDEX IF NOT EXISTS idx_subscriptions_end_date ON subscriptions(end_date)",
 		"CREATE INDEX IF NOT EXISTS idx_subscriptions_service_name ON subscriptions(service_name)",
+		"CREATE INDEX IF NOT EXISTS idx_subscriptions_created_at_id ON subscriptions(created_at, id)",
+		"CREATE INDEX IF NOT EXISTS idx_subscription_events_subscription_id ON subscription_events(subscription_id, created_at)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_prices_subscription_id ON subscription_prices(subscription_id, effective_from)",
 	}
 
 	for _, idx := range indexes {
@@ -35,6 +63,135 @@ func Run(db *gorm.DB) error {
 		}
 	}
 
+	// Пользователи подписок, созданных до появления таблицы users
+	users := `INSERT INTO users (id, created_at, updated_at)
+		SELECT DISTINCT user_id, NOW(), NOW() FROM subscriptions
+		ON CONFLICT (id) DO NOTHING`
+	if err := db.Exec(users).Error; err != nil {
+		return err
+	}
+
+	// Ограничения целостности. NOT VALID не проверяет уже существующие строки,
+	// чтобы миграция не падала на исторических данных
+	constraints := []string{
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'chk_subscriptions_end_after_start') THEN
+				ALTER TABLE subscriptions ADD CONSTRAINT chk_subscriptions_end_after_start
+					CHECK (end_date IS NULL OR end_date >= start_date) NOT VALID;
+			END IF;
+		END $$`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscription_prices_subscription') THEN
+				ALTER TABLE subscription_prices ADD CONSTRAINT fk_subscription_prices_subscription
+					FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE;
+			END IF;
+		END $$`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscription_pauses_subscription') THEN
+				ALTER TABLE subscription_pauses ADD CONSTRAINT fk_subscription_pauses_subscription
+					FOREIGN KEY (subscription_id) REFERENCES subscriptions(id) ON DELETE CASCADE;
+			END IF;
+		END $$`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_service_aliases_service') THEN
+				ALTER TABLE service_aliases ADD CONSTRAINT fk_service_aliases_service
+					FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE CASCADE;
+			END IF;
+		END $$`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscriptions_service') THEN
+				ALTER TABLE subscriptions ADD CONSTRAINT fk_subscriptions_service
+					FOREIGN KEY (service_id) REFERENCES services(id) ON DELETE SET NULL;
+			END IF;
+		END $$`,
+		`DO $$ BEGIN
+			IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_subscriptions_user') THEN
+				ALTER TABLE subscriptions ADD CONSTRAINT fk_subscriptions_user
+					FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+			END IF;
+		END $$`,
+	}
+
+	for _, constraint := range constraints {
+		if err := db.Exec(constraint).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := overlapConstraint(db, overlap.Policy); err != nil {
+		return err
+	}
+
+	// Начальная цена для подписок, созданных до появления шкалы цен
+	backfill := `INSERT INTO subscription_prices (id, subscription_id, effective_from, price, created_at)
+		SELECT uuid_generate_v4(), s.id, s.start_date, s.price, NOW()
+		FROM subscriptions s
+		WHERE NOT EXISTS (SELECT 1 FROM subscription_prices p WHERE p.subscription_id = s.id)`
+	if err := db.Exec(backfill).Error; err != nil {
+		return err
+	}
+
+	// Связь существующих подписок с каталогом сервисов по названию
+	linked, err := repository.NewPostgresSubscriptionRepository(db).LinkServices(context.Background(),
+		repository.EventSource{Actor: repository.SystemActor})
+	if err != nil {
+		return err
+	}
+	if linked > 0 {
+		log.Printf("Linked %d subscriptions to the services catalog", linked)
+	}
+
 	log.Println("Migrations completed")
 	return nil
 }
+
+// overlapConstraint создает ограничение, запрещающее пересечение периодов действующих подписок
+// одного пользователя на один сервис, при политике reject и удаляет его при остальных политиках.
+// Если существующие подписки уже пересекаются, политику reject обеспечить нельзя и запуск прерывается:
+// пересечения нужно исправить, запустив сервис с политикой warn и найдя их через GET /subscriptions/overlaps
+func overlapConstraint(db *gorm.DB, policy string) error {
+	if policy != "reject" {
+		return db.Exec("ALTER TABLE subscriptions DROP CONSTRAINT IF EXISTS " + repository.ConstraintNoOverlap).Error
+	}
+
+	// btree_gist нужен для сравнения user_id и service_name в GiST-индексе ограничения
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS btree_gist").Error; err != nil {
+		log.Printf("Note: btree_gist extension might already exist: %v", err)
+	}
+
+	var exists bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ?)", repository.ConstraintNoOverlap).
+		Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	var overlaps int64
+	count := `SELECT COUNT(*) FROM subscriptions a
+		JOIN subscriptions b ON b.user_id = a.user_id AND b.service_name = a.service_name AND b.id > a.id
+		WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL
+			AND daterange(a.start_date, a.end_date, '[]') && daterange(b.start_date, b.end_date, '[]')`
+	if err := db.Raw(count).Scan(&overlaps).Error; err != nil {
+		return err
+	}
+	if overlaps > 0 {
+		return fmt.Errorf("overlap policy reject cannot be enforced: %d pairs of existing subscriptions overlap; "+
+			"start with OVERLAP_POLICY=warn and fix them using GET /api/v1/subscriptions/overlaps", overlaps)
+	}
+
+	constraint := `DO $$ BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = '` + repository.ConstraintNoOverlap + `') THEN
+			ALTER TABLE subscriptions ADD CONSTRAINT ` + repository.ConstraintNoOverlap + ` EXCLUDE USING gist (
+				user_id WITH =,
+				service_name WITH =,
+				daterange(start_date, end_date, '[]') WITH &&
+			) WHERE (deleted_at IS NULL);
+		END IF;
+	END $$`
+	if err := db.Exec(constraint).Error; err != nil {
+		return fmt.Errorf("failed to create overlap constraint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// IdempotencyKey - результат запроса, выполненного с заголовком Idempotency-Key.
+// Повтор запроса с тем же ключом до ExpiresAt возвращает сохраненный ответ.
+type IdempotencyKey struct {
+	Key string `gorm:"type:varchar(255);primary_key"`
+	// RequestHash - SHA-256 данных запроса: повтор с другими данными отклоняется
+	RequestHash string    `gorm:"type:varchar(64);not null"`
+	Response    []byte    `gorm:"type:bytea;not null"`
+	CreatedAt   time.Time `gorm:"not null"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Service - сервис из каталога, на который оформляются подписки
+type Service struct {
+	ID   uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name string    `gorm:"type:varchar(255);not null" json:"name"`
+	// Category - категория сервиса, например "video" или "music"
+	Category string `gorm:"type:varchar(64);not null;default:''" json:"category"`
+	// DefaultPrice - обычная цена подписки на сервис в базовой валюте
+	DefaultPrice *int      `gorm:"type:integer" json:"default_price,omitempty"`
+	VendorURL    string    `gorm:"type:varchar(2048);not null;default:''" json:"vendor_url"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+
+	// Aliases - другие названия сервиса, которые при создании подписки заменяются на Name
+	Aliases []string `gorm:"-" json:"aliases"`
+}
+
+func (s *Service) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// ServiceAlias - название сервиса, по которому он находится в каталоге. Название из Service.Name
+// тоже хранится как псевдоним, поэтому нормализованные названия уникальны во всем каталоге
+type ServiceAlias struct {
+	// Normalized - название, приведенное NormalizeServiceName
+	Normalized string    `gorm:"type:varchar(255);primary_key"`
+	ServiceID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	Alias      string    `gorm:"type:varchar(255);not null"`
+}
+
+// NormalizeServiceName приводит название сервиса к виду для сравнения:
+// нижний регистр, без пробелов по краям и с одиночными пробелами между словами
+func NormalizeServiceName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -7,21 +7,70 @@ import (
 	"gorm.io/gorm"
 )
 
+// Периоды оплаты подписки
+const (
+	BillingPeriodWeekly    = "weekly"
+	BillingPeriodMonthly   = "monthly"
+	BillingPeriodQuarterly = "quarterly"
+	BillingPeriodAnnual    = "annual"
+)
+
+// Статусы подписки. Время последнего перехода в paused, active (возобновления) и cancelled
+// хранится в PausedAt, ResumedAt и CancelledAt
+const (
+	// StatusTrial - идет бесплатный пробный период подписки
+	StatusTrial = "trial"
+	// StatusActive - подписка действует и оплачивается
+	StatusActive = "active"
+	// StatusPaused - подписка приостановлена, месяцы приостановки не оплачиваются
+	StatusPaused = "paused"
+	// StatusCancelled - подписка отменена и действует до конца оплаченного периода
+	StatusCancelled = "cancelled"
+	// StatusExpired - дата окончания подписки прошла
+	StatusExpired = "expired"
+)
+
 type Subscription struct {
-	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
-	ServiceName string         `gorm:"type:varchar(255);not null" json:"service_name"`
-	Price       int            `gorm:"type:integer;not null" json:"price"`
-	UserID      uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	StartDate   time.Time      `gorm:"type:date;not null;index" json:"start_date"`
-	EndDate     *time.Time     `gorm:"type:date;index" json:"end_date,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uuid.UUID      `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	ServiceName   string         `gorm:"type:varchar(255);not null" json:"service_name"`
+	ServiceID     *uuid.UUID     `gorm:"type:uuid;index" json:"service_id,omitempty"`
+	Price         int            `gorm:"type:integer;not null" json:"price"`
+	Currency      string         `gorm:"type:varchar(3);not null" json:"currency"`
+	BillingPeriod string         `gorm:"type:varchar(16);not null;default:'monthly'" json:"billing_period"`
+	BillingDay    *int           `gorm:"type:smallint" json:"billing_day,omitempty"`
+	UserID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	StartDate     time.Time      `gorm:"type:date;not null;index" json:"start_date"`
+	EndDate       *time.Time     `gorm:"type:date;index" json:"end_date,omitempty"`
+	TrialMonths   int            `gorm:"type:integer;not null;default:0" json:"trial_months,omitempty"`
+	IntroPrice    *int           `gorm:"type:integer" json:"intro_price,omitempty"`
+	IntroMonths   int            `gorm:"type:integer;not null;default:0" json:"intro_months,omitempty"`
+	Status        string         `gorm:"type:varchar(16);not null;default:'active';index" json:"status"`
+	PausedAt      *time.Time     `json:"paused_at,omitempty"`
+	ResumedAt     *time.Time     `json:"resumed_at,omitempty"`
+	CancelledAt   *time.Time     `json:"cancelled_at,omitempty"`
+	Version       int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Prices - шкала цен по возрастанию EffectiveFrom. Заполняется только для расчета стоимости.
+	// Price - последняя цена шкалы, даже если она действует с будущего месяца
+	Prices []SubscriptionPrice `gorm:"-" json:"-"`
+	// Pauses - приостановки по возрастанию PausedFrom. Заполняется только для расчета стоимости
+	Pauses []SubscriptionPause `gorm:"-" json:"-"`
+	// Overlaps - ID пересекающихся подписок того же пользователя на тот же сервис.
+	// Заполняется при создании и изменении подписки, если пересечения разрешены с предупреждением
+	Overlaps []uuid.UUID `gorm:"-" json:"-"`
+	// NextChargeDate - дата следующего списания, вычисляется при выдаче подписки
+	NextChargeDate *time.Time `gorm:"-" json:"next_charge_date,omitempty"`
 }
 
 func (s *Subscription) BeforeCreate(tx *gorm.DB) error {
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
 	}
+	if s.Version == 0 {
+		s.Version = 1
+	}
 	return nil
 }
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Действия над подпиской, записываемые в журнал
+const (
+	EventCreated     = "created"
+	EventUpdated     = "updated"
+	EventDeleted     = "deleted"
+	EventRestored    = "restored"
+	EventHardDeleted = "hard_deleted"
+	EventPaused      = "paused"
+	EventResumed     = "resumed"
+	EventCancelled   = "cancelled"
+	EventExpired     = "expired"
+	EventTrialEnded  = "trial_ended"
+)
+
+// FieldChange - значение поля подписки до и после изменения
+type FieldChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// EventChanges - изменившиеся поля подписки. Хранится в jsonb.
+type EventChanges map[string]FieldChange
+
+func (c EventChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (c *EventChanges) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c = nil
+		return nil
+	default:
+		return fmt.Errorf("unsupported type %T for EventChanges", src)
+	}
+	return json.Unmarshal(data, c)
+}
+
+// SubscriptionEvent - запись журнала изменений подписки
+type SubscriptionEvent struct {
+	ID             uuid.UUID    `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	SubscriptionID uuid.UUID    `gorm:"type:uuid;not null" json:"subscription_id"`
+	Action         string       `gorm:"type:varchar(32);not null" json:"action"`
+	Actor          string       `gorm:"type:varchar(255);not null" json:"actor"`
+	RequestID      string       `gorm:"type:varchar(128)" json:"request_id,omitempty"`
+	Changes        EventChanges `gorm:"type:jsonb;not null" json:"changes"`
+	CreatedAt      time.Time    `json:"created_at"`
+}
+
+func (e *SubscriptionEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
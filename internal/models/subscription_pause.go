@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionPause - приостановка подписки: месяцы с PausedFrom до ResumedFrom (не включая его)
+// не оплачиваются. Пустой ResumedFrom означает, что подписка ещё приостановлена
+type SubscriptionPause struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"-"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"-"`
+	PausedFrom     time.Time  `gorm:"type:date;not null" json:"paused_from"`
+	ResumedFrom    *time.Time `gorm:"type:date" json:"resumed_from,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (p *SubscriptionPause) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Covers сообщает, приходится ли месяц month на приостановку
+func (p SubscriptionPause) Covers(month time.Time) bool {
+	return !month.Before(p.PausedFrom) && (p.ResumedFrom == nil || month.Before(*p.ResumedFrom))
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubscriptionPrice - цена подписки, действующая с месяца EffectiveFrom до следующего изменения
+type SubscriptionPrice struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"-"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null" json:"-"`
+	EffectiveFrom  time.Time `gorm:"type:date;not null" json:"effective_from"`
+	Price          int       `gorm:"type:integer;not null" json:"price"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func (p *SubscriptionPrice) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// User - пользователь, которому принадлежат подписки. Запись создается автоматически
+// при первой подписке пользователя, имя и email заполняются отдельно
+type User struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:uuid_generate_v4()" json:"id"`
+	Name      string    `gorm:"type:varchar(255);not null;default:''" json:"name"`
+	Email     string    `gorm:"type:varchar(255);not null;default:''" json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
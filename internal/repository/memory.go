@@ -0,0 +1,1040 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type memorySubscriptionRepository struct {
+	mu            sync.RWMutex
+	subscriptions map[uuid.UUID]models.Subscription
+	prices        map[uuid.UUID][]models.SubscriptionPrice
+	pauses        map[uuid.UUID][]models.SubscriptionPause
+	events        []models.SubscriptionEvent
+	keys          map[string]models.IdempotencyKey
+	services      map[uuid.UUID]models.Service
+	aliases       map[string]models.ServiceAlias
+	users         map[uuid.UUID]models.User
+	// txMu упорядочивает транзакции между собой
+	txMu sync.Mutex
+}
+
+// NewMemorySubscriptionRepository создает хранилище подписок в памяти.
+// Используется в тестах и для запуска без базы данных.
+func NewMemorySubscriptionRepository() SubscriptionRepository {
+	return &memorySubscriptionRepository{
+		subscriptions: make(map[uuid.UUID]models.Subscription),
+		prices:        make(map[uuid.UUID][]models.SubscriptionPrice),
+		pauses:        make(map[uuid.UUID][]models.SubscriptionPause),
+		keys:          make(map[string]models.IdempotencyKey),
+		services:      make(map[uuid.UUID]models.Service),
+		aliases:       make(map[string]models.ServiceAlias),
+		users:         make(map[uuid.UUID]models.User),
+	}
+}
+
+// cloneSubscription копирует подписку, чтобы вызывающий код не менял данные хранилища
+func cloneSubscription(sub models.Subscription) models.Subscription {
+	if sub.ServiceID != nil {
+		serviceID := *sub.ServiceID
+		sub.ServiceID = &serviceID
+	}
+	if sub.EndDate != nil {
+		endDate := *sub.EndDate
+		sub.EndDate = &endDate
+	}
+	sub.BillingDay = cloneInt(sub.BillingDay)
+	sub.IntroPrice = cloneInt(sub.IntroPrice)
+	sub.NextChargeDate = cloneTime(sub.NextChargeDate)
+	sub.PausedAt = cloneTime(sub.PausedAt)
+	sub.ResumedAt = cloneTime(sub.ResumedAt)
+	sub.CancelledAt = cloneTime(sub.CancelledAt)
+	sub.Prices = append([]models.SubscriptionPrice(nil), sub.Prices...)
+	sub.Pauses = clonePauses(sub.Pauses)
+	return sub
+}
+
+// stored возвращает копию подписки для сохранения без полей, которые не хранятся в БД
+func stored(sub models.Subscription) models.Subscription {
+	sub = cloneSubscription(sub)
+	sub.Prices = nil
+	sub.Pauses = nil
+	sub.Overlaps = nil
+	sub.NextChargeDate = nil
+	return sub
+}
+
+// cloneTime копирует необязательное время
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	return &copied
+}
+
+// cloneInt копирует необязательное число
+func cloneInt(n *int) *int {
+	if n == nil {
+		return nil
+	}
+	copied := *n
+	return &copied
+}
+
+// clonePauses копирует приостановки вместе с датами возобновления
+func clonePauses(pauses []models.SubscriptionPause) []models.SubscriptionPause {
+	if pauses == nil {
+		return nil
+	}
+	cloned := make([]models.SubscriptionPause, len(pauses))
+	for i, pause := range pauses {
+		pause.ResumedFrom = cloneTime(pause.ResumedFrom)
+		cloned[i] = pause
+	}
+	return cloned
+}
+
+// alive сообщает, что подписка не удалена
+func alive(sub models.Subscription) bool {
+	return !sub.DeletedAt.Valid
+}
+
+func (r *memorySubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if sub.ID == uuid.Nil {
+		sub.ID = uuid.New()
+	}
+	if _, exists := r.subscriptions[sub.ID]; exists {
+		return fmt.Errorf("subscription %s already exists", sub.ID)
+	}
+	if !validDates(*sub) {
+		return ErrInvalidDates
+	}
+
+	if sub.Version == 0 {
+		sub.Version = 1
+	}
+	if sub.Status == "" {
+		sub.Status = models.StatusActive
+	}
+	now := time.Now().UTC()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	r.subscriptions[sub.ID] = stored(*sub)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok || !alive(sub) {
+		return nil, ErrNotFound
+	}
+	sub = cloneSubscription(sub)
+	return &sub, nil
+}
+
+func (r *memorySubscriptionRepository) GetWithDeleted(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	sub = cloneSubscription(sub)
+	return &sub, nil
+}
+
+func (r *memorySubscriptionRepository) Update(ctx context.Context, sub *models.Subscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.subscriptions[sub.ID]
+	if !ok || !alive(existing) {
+		return ErrNotFound
+	}
+	if existing.Version != sub.Version {
+		return ErrVersionConflict
+	}
+	if !validDates(*sub) {
+		return ErrInvalidDates
+	}
+
+	sub.Version++
+	sub.CreatedAt = existing.CreatedAt
+	sub.UpdatedAt = time.Now().UTC()
+	r.subscriptions[sub.ID] = stored(*sub)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Delete(ctx context.Context, id uuid.UUID, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok || !alive(sub) {
+		return ErrNotFound
+	}
+	if version != 0 && sub.Version != version {
+		return ErrVersionConflict
+	}
+
+	sub.DeletedAt = gorm.DeletedAt{Time: time.Now().UTC(), Valid: true}
+	r.subscriptions[id] = sub
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if alive(sub) {
+		return nil, ErrNotDeleted
+	}
+
+	sub.DeletedAt = gorm.DeletedAt{}
+	sub.Version++
+	sub.UpdatedAt = time.Now().UTC()
+	r.subscriptions[id] = sub
+
+	restored := cloneSubscription(sub)
+	return &restored, nil
+}
+
+func (r *memorySubscriptionRepository) HardDelete(ctx context.Context, id uuid.UUID, versions []int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sub, ok := r.subscriptions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if versions != nil && !containsVersion(versions, sub.Version) {
+		return ErrVersionConflict
+	}
+
+	delete(r.subscriptions, id)
+	delete(r.prices, id)
+	delete(r.pauses, id)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for id, sub := range r.subscriptions {
+		if !alive(sub) && sub.DeletedAt.Time.Before(before) {
+			delete(r.subscriptions, id)
+			delete(r.prices, id)
+			delete(r.pauses, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *memorySubscriptionRepository) Expire(ctx context.Context, month time.Time, source EventSource) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired int64
+	now := time.Now().UTC()
+	for id, sub := range r.subscriptions {
+		if alive(sub) && sub.Status != models.StatusExpired && sub.EndDate != nil && sub.EndDate.Before(month) {
+			r.logEvent(source.event(id, models.EventExpired, statusChanges(sub, models.StatusExpired)), now)
+			sub.Status = models.StatusExpired
+			sub.Version++
+			sub.UpdatedAt = now
+			r.subscriptions[id] = sub
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+func (r *memorySubscriptionRepository) EndTrials(ctx context.Context, month time.Time, source EventSource) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ended int64
+	now := time.Now().UTC()
+	for id, sub := range r.subscriptions {
+		if alive(sub) && sub.Status == models.StatusTrial && !sub.StartDate.AddDate(0, sub.TrialMonths, 0).After(month) {
+			r.logEvent(source.event(id, models.EventTrialEnded, statusChanges(sub, models.StatusActive)), now)
+			sub.Status = models.StatusActive
+			sub.Version++
+			sub.UpdatedAt = now
+			r.subscriptions[id] = sub
+			ended++
+		}
+	}
+	return ended, nil
+}
+
+// containsVersion сообщает, входит ли версия в список
+func containsVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memorySubscriptionRepository) List(ctx context.Context, filter ListFilter, page Page) (*ListResult, error) {
+	var less func(a, b models.Subscription) bool
+	if page.UseCursor {
+		less = func(a, b models.Subscription) bool {
+			if page.Desc {
+				a, b = b, a
+			}
+			return cursorLess(a, b)
+		}
+	} else {
+		var err error
+		if less, err = sortLess(page.Sort); err != nil {
+			return nil, err
+		}
+	}
+
+	items := r.filtered(filter)
+	sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+
+	result := &ListResult{}
+	if page.WithTotal {
+		result.Total = int64(len(items))
+	}
+
+	if page.UseCursor {
+		start := 0
+		if page.After != nil {
+			after := models.Subscription{CreatedAt: page.After.CreatedAt, ID: page.After.ID}
+			start = sort.Search(len(items), func(i int) bool { return less(after, items[i]) })
+		}
+		items = items[start:]
+		if len(items) > page.Limit {
+			items = items[:page.Limit]
+			last := items[len(items)-1]
+			result.NextCursor = &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		result.Items = items
+		return result, nil
+	}
+
+	if page.Offset >= len(items) {
+		result.Items = []models.Subscription{}
+		return result, nil
+	}
+	items = items[page.Offset:]
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+	}
+	result.Items = items
+	return result, nil
+}
+
+func (r *memorySubscriptionRepository) Each(ctx context.Context, filter ListFilter, sortFields []SortField, fn func(sub *models.Subscription) error) error {
+	less, err := sortLess(sortFields)
+	if err != nil {
+		return err
+	}
+
+	items := r.filtered(filter)
+	sort.Slice(items, func(i, j int) bool { return less(items[i], items[j]) })
+	for i := range items {
+		if err := fn(&items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filtered возвращает копии подписок, подходящих под фильтр списка
+func (r *memorySubscriptionRepository) filtered(filter ListFilter) []models.Subscription {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var items []models.Subscription
+	for _, sub := range r.subscriptions {
+		if alive(sub) != filter.Deleted && matchesFilter(sub, filter) {
+			items = append(items, cloneSubscription(sub))
+		}
+	}
+	return items
+}
+
+func (r *memorySubscriptionRepository) Aggregate(ctx context.Context, filter AggregateFilter) ([]models.Subscription, error) {
+	r.mu.RLock()
+	var subscriptions []models.Subscription
+	for _, sub := range r.subscriptions {
+		if !alive(sub) || !activeInPeriod(sub, filter.From, filter.To) {
+			continue
+		}
+		if filter.UserID != nil && sub.UserID != *filter.UserID {
+			continue
+		}
+		if !hasService(sub, filter.ServiceID) || (filter.ServiceName != "" && sub.ServiceName != filter.ServiceName) {
+			continue
+		}
+		sub = cloneSubscription(sub)
+		sub.Prices = append([]models.SubscriptionPrice(nil), r.prices[sub.ID]...)
+		sub.Pauses = clonePauses(r.pauses[sub.ID])
+		subscriptions = append(subscriptions, sub)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if !a.StartDate.Equal(b.StartDate) {
+			return a.StartDate.Before(b.StartDate)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+	return subscriptions, nil
+}
+
+func (r *memorySubscriptionRepository) Overlapping(ctx context.Context, sub models.Subscription) ([]models.Subscription, error) {
+	r.mu.RLock()
+	var subscriptions []models.Subscription
+	for _, other := range r.subscriptions {
+		if alive(other) && other.ID != sub.ID && sameOwner(other, sub) && periodsOverlap(other, sub) {
+			subscriptions = append(subscriptions, cloneSubscription(other))
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(subscriptions, func(i, j int) bool { return startLess(subscriptions[i], subscriptions[j]) })
+	return subscriptions, nil
+}
+
+func (r *memorySubscriptionRepository) Overlaps(ctx context.Context, filter OverlapFilter) ([]OverlapPair, error) {
+	r.mu.RLock()
+	var subscriptions []models.Subscription
+	for _, sub := range r.subscriptions {
+		if !alive(sub) || (filter.UserID != nil && sub.UserID != *filter.UserID) || !hasService(sub, filter.ServiceID) ||
+			(filter.ServiceName != "" && sub.ServiceName != filter.ServiceName) {
+			continue
+		}
+		subscriptions = append(subscriptions, cloneSubscription(sub))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.UserID != b.UserID {
+			return a.UserID.String() < b.UserID.String()
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return startLess(a, b)
+	})
+
+	pairs := []OverlapPair{}
+	for i, a := range subscriptions {
+		for _, b := range subscriptions[i+1:] {
+			if !sameOwner(a, b) {
+				break
+			}
+			if periodsOverlap(a, b) {
+				pairs = append(pairs, OverlapPair{A: a, B: b})
+			}
+		}
+	}
+	return pairs, nil
+}
+
+// hasService сообщает, что подписка связана с сервисом каталога serviceID. nil не фильтрует
+func hasService(sub models.Subscription, serviceID *uuid.UUID) bool {
+	return serviceID == nil || (sub.ServiceID != nil && *sub.ServiceID == *serviceID)
+}
+
+// sameOwner сообщает, что подписки принадлежат одному пользователю и относятся к одному сервису
+func sameOwner(a, b models.Subscription) bool {
+	return a.UserID == b.UserID && a.ServiceName == b.ServiceName
+}
+
+// startLess упорядочивает подписки по (start_date, id)
+func startLess(a, b models.Subscription) bool {
+	if !a.StartDate.Equal(b.StartDate) {
+		return a.StartDate.Before(b.StartDate)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func (r *memorySubscriptionRepository) Prices(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPrice, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return append([]models.SubscriptionPrice(nil), r.prices[subscriptionID]...), nil
+}
+
+func (r *memorySubscriptionRepository) SetPrice(ctx context.Context, price *models.SubscriptionPrice) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if price.ID == uuid.Nil {
+		price.ID = uuid.New()
+	}
+	price.CreatedAt = time.Now().UTC()
+
+	var timeline []models.SubscriptionPrice
+	for _, existing := range r.prices[price.SubscriptionID] {
+		if existing.EffectiveFrom.Before(price.EffectiveFrom) {
+			timeline = append(timeline, existing)
+		}
+	}
+	r.prices[price.SubscriptionID] = append(timeline, *price)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) Pauses(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPause, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return clonePauses(r.pauses[subscriptionID]), nil
+}
+
+func (r *memorySubscriptionRepository) SavePause(ctx context.Context, pause *models.SubscriptionPause) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pauses := r.pauses[pause.SubscriptionID]
+	for i, existing := range pauses {
+		if existing.ID == pause.ID {
+			pauses[i] = clonePauses([]models.SubscriptionPause{*pause})[0]
+			return nil
+		}
+	}
+
+	if pause.ID == uuid.Nil {
+		pause.ID = uuid.New()
+	}
+	pause.CreatedAt = time.Now().UTC()
+	pauses = append(pauses, clonePauses([]models.SubscriptionPause{*pause})[0])
+	sort.Slice(pauses, func(i, j int) bool {
+		return pauses[i].PausedFrom.Before(pauses[j].PausedFrom)
+	})
+	r.pauses[pause.SubscriptionID] = pauses
+	return nil
+}
+
+func (r *memorySubscriptionRepository) AddEvent(ctx context.Context, event *models.SubscriptionEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if event.ID == uuid.Nil {
+		event.ID = uuid.New()
+	}
+	event.CreatedAt = time.Now().UTC()
+	r.events = append(r.events, *event)
+	return nil
+}
+
+// logEvent записывает в журнал изменение подписки, сделанное самим хранилищем. Вызывается под r.mu
+func (r *memorySubscriptionRepository) logEvent(event models.SubscriptionEvent, now time.Time) {
+	event.ID = uuid.New()
+	event.CreatedAt = now
+	r.events = append(r.events, event)
+}
+
+func (r *memorySubscriptionRepository) History(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []models.SubscriptionEvent
+	for _, event := range r.events {
+		if event.SubscriptionID == subscriptionID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *memorySubscriptionRepository) IdempotencyKey(ctx context.Context, key string, now time.Time) (*models.IdempotencyKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	record, ok := r.keys[key]
+	if !ok || !record.ExpiresAt.After(now) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	return &record, nil
+}
+
+func (r *memorySubscriptionRepository) SaveIdempotencyKey(ctx context.Context, record *models.IdempotencyKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.keys[record.Key]; ok && existing.ExpiresAt.After(record.CreatedAt) {
+		return ErrIdempotencyKeyExists
+	}
+	r.keys[record.Key] = *record
+	return nil
+}
+
+func (r *memorySubscriptionRepository) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var purged int64
+	for key, record := range r.keys {
+		if !record.ExpiresAt.After(now) {
+			delete(r.keys, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *memorySubscriptionRepository) CreateService(ctx context.Context, svc *models.Service, source EventSource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if svc.ID == uuid.Nil {
+		svc.ID = uuid.New()
+	}
+	if err := r.checkAliases(svc); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	svc.CreatedAt = now
+	svc.UpdatedAt = now
+	r.services[svc.ID] = storedService(*svc)
+	r.addAliases(svc)
+	r.linkServices(now, source)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	svc, ok := r.services[id]
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	svc = r.withAliases(svc)
+	return &svc, nil
+}
+
+func (r *memorySubscriptionRepository) ListServices(ctx context.Context, filter ServiceFilter) ([]models.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	search := models.NormalizeServiceName(filter.Search)
+	matched := make(map[uuid.UUID]bool)
+	for normalized, alias := range r.aliases {
+		if strings.Contains(normalized, search) {
+			matched[alias.ServiceID] = true
+		}
+	}
+
+	services := []models.Service{}
+	for id, svc := range r.services {
+		if matched[id] && (filter.Category == "" || svc.Category == filter.Category) {
+			services = append(services, r.withAliases(svc))
+		}
+	}
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Name != services[j].Name {
+			return services[i].Name < services[j].Name
+		}
+		return services[i].ID.String() < services[j].ID.String()
+	})
+	return services, nil
+}
+
+func (r *memorySubscriptionRepository) UpdateService(ctx context.Context, svc *models.Service, source EventSource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.services[svc.ID]
+	if !ok {
+		return ErrServiceNotFound
+	}
+	if err := r.checkAliases(svc); err != nil {
+		return err
+	}
+
+	svc.CreatedAt = existing.CreatedAt
+	svc.UpdatedAt = time.Now().UTC()
+	r.services[svc.ID] = storedService(*svc)
+	r.removeAliases(svc.ID)
+	r.addAliases(svc)
+
+	for _, sub := range r.subscriptions {
+		if hasService(sub, &svc.ID) && sub.ServiceName != svc.Name {
+			r.changeService(sub, sub.ServiceID, svc.Name, svc.UpdatedAt, source)
+		}
+	}
+	r.linkServices(svc.UpdatedAt, source)
+	*svc = r.withAliases(r.services[svc.ID])
+	return nil
+}
+
+func (r *memorySubscriptionRepository) DeleteService(ctx context.Context, id uuid.UUID, source EventSource) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.services[id]; !ok {
+		return ErrServiceNotFound
+	}
+	now := time.Now().UTC()
+	for _, sub := range r.subscriptions {
+		if hasService(sub, &id) {
+			r.changeService(sub, nil, sub.ServiceName, now, source)
+		}
+	}
+	r.removeAliases(id)
+	delete(r.services, id)
+	return nil
+}
+
+func (r *memorySubscriptionRepository) ServiceByName(ctx context.Context, name string) (*models.Service, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	alias, ok := r.aliases[models.NormalizeServiceName(name)]
+	if !ok {
+		return nil, ErrServiceNotFound
+	}
+	svc := r.services[alias.ServiceID]
+	return &svc, nil
+}
+
+func (r *memorySubscriptionRepository) LinkServices(ctx context.Context, source EventSource) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.linkServices(time.Now().UTC(), source), nil
+}
+
+// linkServices связывает подписки без ссылки на каталог с сервисами по названию. Вызывается под r.mu
+func (r *memorySubscriptionRepository) linkServices(now time.Time, source EventSource) int64 {
+	var linked int64
+	for _, sub := range r.subscriptions {
+		if sub.ServiceID != nil {
+			continue
+		}
+		alias, ok := r.aliases[models.NormalizeServiceName(sub.ServiceName)]
+		if !ok {
+			continue
+		}
+		serviceID := alias.ServiceID
+		r.changeService(sub, &serviceID, r.services[serviceID].Name, now, source)
+		linked++
+	}
+	return linked
+}
+
+// changeService переводит подписку на сервис serviceID с названием name и записывает изменение в журнал.
+// Вызывается под r.mu
+func (r *memorySubscriptionRepository) changeService(sub models.Subscription, serviceID *uuid.UUID, name string, now time.Time, source EventSource) {
+	r.logEvent(source.event(sub.ID, models.EventUpdated, serviceChanges(sub, serviceID, name)), now)
+	sub.ServiceID = serviceID
+	sub.ServiceName = name
+	sub.Version++
+	sub.UpdatedAt = now
+	r.subscriptions[sub.ID] = sub
+}
+
+// storedService возвращает копию сервиса для сохранения: псевдонимы хранятся отдельно
+func storedService(svc models.Service) models.Service {
+	if svc.DefaultPrice != nil {
+		price := *svc.DefaultPrice
+		svc.DefaultPrice = &price
+	}
+	svc.Aliases = nil
+	return svc
+}
+
+// withAliases возвращает копию сервиса с псевдонимами, кроме его собственного названия
+func (r *memorySubscriptionRepository) withAliases(svc models.Service) models.Service {
+	svc = storedService(svc)
+	svc.Aliases = []string{}
+	for _, alias := range r.aliases {
+		if alias.ServiceID == svc.ID && alias.Alias != svc.Name {
+			svc.Aliases = append(svc.Aliases, alias.Alias)
+		}
+	}
+	sort.Strings(svc.Aliases)
+	return svc
+}
+
+// checkAliases повторяет уникальный индекс псевдонимов: название и псевдонимы не должны быть заняты другими сервисами
+func (r *memorySubscriptionRepository) checkAliases(svc *models.Service) error {
+	seen := make(map[string]bool)
+	for _, alias := range append([]string{svc.Name}, svc.Aliases...) {
+		normalized := models.NormalizeServiceName(alias)
+		if existing, ok := r.aliases[normalized]; (ok && existing.ServiceID != svc.ID) || seen[normalized] {
+			return ErrServiceAliasExists
+		}
+		seen[normalized] = true
+	}
+	return nil
+}
+
+// addAliases сохраняет название и псевдонимы сервиса
+func (r *memorySubscriptionRepository) addAliases(svc *models.Service) {
+	for _, alias := range append([]string{svc.Name}, svc.Aliases...) {
+		normalized := models.NormalizeServiceName(alias)
+		r.aliases[normalized] = models.ServiceAlias{Normalized: normalized, ServiceID: svc.ID, Alias: alias}
+	}
+}
+
+// removeAliases удаляет название и псевдонимы сервиса
+func (r *memorySubscriptionRepository) removeAliases(serviceID uuid.UUID) {
+	for normalized, alias := range r.aliases {
+		if alias.ServiceID == serviceID {
+			delete(r.aliases, normalized)
+		}
+	}
+}
+
+func (r *memorySubscriptionRepository) CreateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if _, ok := r.users[user.ID]; ok {
+		return ErrUserExists
+	}
+	now := time.Now().UTC()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.users[user.ID] = *user
+	return nil
+}
+
+func (r *memorySubscriptionRepository) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (r *memorySubscriptionRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[user.ID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	existing.Name = user.Name
+	existing.Email = user.Email
+	existing.UpdatedAt = time.Now().UTC()
+	r.users[user.ID] = existing
+	*user = existing
+	return nil
+}
+
+func (r *memorySubscriptionRepository) EnsureUser(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		now := time.Now().UTC()
+		r.users[id] = models.User{ID: id, CreatedAt: now, UpdatedAt: now}
+	}
+	return nil
+}
+
+// InTx выполняет fn над тем же хранилищем и при ошибке откатывает его к состоянию до транзакции.
+// Транзакции выполняются по очереди, но не изолированы от вызовов вне транзакций.
+func (r *memorySubscriptionRepository) InTx(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	return r.withRollback(fn)
+}
+
+// withRollback выполняет fn и при ошибке восстанавливает данные хранилища
+func (r *memorySubscriptionRepository) withRollback(fn func(repo SubscriptionRepository) error) error {
+	r.mu.RLock()
+	subscriptions := make(map[uuid.UUID]models.Subscription, len(r.subscriptions))
+	for id, sub := range r.subscriptions {
+		subscriptions[id] = cloneSubscription(sub)
+	}
+	prices := make(map[uuid.UUID][]models.SubscriptionPrice, len(r.prices))
+	for id, timeline := range r.prices {
+		prices[id] = append([]models.SubscriptionPrice(nil), timeline...)
+	}
+	pauses := make(map[uuid.UUID][]models.SubscriptionPause, len(r.pauses))
+	for id, subscriptionPauses := range r.pauses {
+		pauses[id] = clonePauses(subscriptionPauses)
+	}
+	events := len(r.events)
+	keys := make(map[string]models.IdempotencyKey, len(r.keys))
+	for key, record := range r.keys {
+		keys[key] = record
+	}
+	services := make(map[uuid.UUID]models.Service, len(r.services))
+	for id, svc := range r.services {
+		services[id] = svc
+	}
+	aliases := make(map[string]models.ServiceAlias, len(r.aliases))
+	for normalized, alias := range r.aliases {
+		aliases[normalized] = alias
+	}
+	users := make(map[uuid.UUID]models.User, len(r.users))
+	for id, user := range r.users {
+		users[id] = user
+	}
+	r.mu.RUnlock()
+
+	if err := fn(memoryTx{r}); err != nil {
+		r.mu.Lock()
+		r.subscriptions = subscriptions
+		r.prices = prices
+		r.pauses = pauses
+		r.events = r.events[:events]
+		r.keys = keys
+		r.services = services
+		r.aliases = aliases
+		r.users = users
+		r.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// memoryTx - хранилище внутри транзакции. Вложенная транзакция откатывает
+// только свои изменения, как точка сохранения
+type memoryTx struct {
+	*memorySubscriptionRepository
+}
+
+func (t memoryTx) InTx(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	return t.withRollback(fn)
+}
+
+// validDates повторяет CHECK-ограничение таблицы: дата окончания не раньше даты начала
+func validDates(sub models.Subscription) bool {
+	return sub.EndDate == nil || !sub.EndDate.Before(sub.StartDate)
+}
+
+// matchesFilter проверяет подписку на соответствие фильтрам списка
+func matchesFilter(sub models.Subscription, filter ListFilter) bool {
+	if filter.UserID != nil && sub.UserID != *filter.UserID {
+		return false
+	}
+	if !hasService(sub, filter.ServiceID) {
+		return false
+	}
+	if filter.ServiceName != "" && sub.ServiceName != filter.ServiceName {
+		return false
+	}
+	if filter.Search != "" && !strings.Contains(strings.ToLower(sub.ServiceName), strings.ToLower(filter.Search)) {
+		return false
+	}
+	if filter.Currency != "" && sub.Currency != filter.Currency {
+		return false
+	}
+	if filter.MinPrice != nil && sub.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && sub.Price > *filter.MaxPrice {
+		return false
+	}
+	if filter.ActiveAt != nil && !activeInPeriod(sub, *filter.ActiveAt, *filter.ActiveAt) {
+		return false
+	}
+	if filter.HasEndDate != nil && (sub.EndDate != nil) != *filter.HasEndDate {
+		return false
+	}
+	if filter.Status != "" && sub.Status != filter.Status {
+		return false
+	}
+	return true
+}
+
+// sortLess возвращает сравнение подписок по полям sort, при равенстве - по id
+func sortLess(sortFields []SortField) (func(a, b models.Subscription) bool, error) {
+	for _, s := range sortFields {
+		if !IsSortable(s.Field) {
+			return nil, fmt.Errorf("invalid sort field %q", s.Field)
+		}
+	}
+	return func(a, b models.Subscription) bool {
+		for _, s := range sortFields {
+			cmp := compareField(a, b, s.Field)
+			if s.Desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return a.ID.String() < b.ID.String()
+	}, nil
+}
+
+// cursorLess сравнивает подписки по (created_at, id)
+func cursorLess(a, b models.Subscription) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+// compareTime сравнивает даты, отсутствующая дата считается наибольшей (как NULL в PostgreSQL)
+func compareTime(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// compareField сравнивает подписки по одному из полей SortableFields
+func compareField(a, b models.Subscription, field string) int {
+	switch field {
+	case "service_name":
+		return strings.Compare(a.ServiceName, b.ServiceName)
+	case "price":
+		return a.Price - b.Price
+	case "start_date":
+		return a.StartDate.Compare(b.StartDate)
+	case "end_date":
+		return compareTime(a.EndDate, b.EndDate)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		return a.UpdatedAt.Compare(b.UpdatedAt)
+	}
+	return 0
+}
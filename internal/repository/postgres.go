@@ -0,0 +1,838 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Коды ошибок PostgreSQL
+const (
+	pgUniqueViolation    = "23505"
+	pgCheckViolation     = "23514"
+	pgExclusionViolation = "23P01"
+)
+
+// Ограничения таблицы subscriptions, создаваемые в миграциях
+const (
+	constraintEndAfterStart = "chk_subscriptions_end_after_start"
+	// ConstraintNoOverlap запрещает пересечение периодов действующих подписок
+	// одного пользователя на один сервис. Создается только при политике reject
+	ConstraintNoOverlap = "excl_subscriptions_no_overlap"
+)
+
+// translateError переводит ошибки PostgreSQL в ошибки хранилища
+func translateError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case pgErr.Code == pgCheckViolation && pgErr.ConstraintName == constraintEndAfterStart:
+		return ErrInvalidDates
+	case pgErr.Code == pgExclusionViolation && pgErr.ConstraintName == ConstraintNoOverlap:
+		return ErrOverlap
+	case pgErr.Code == pgUniqueViolation && pgErr.TableName == "service_aliases":
+		return ErrServiceAliasExists
+	}
+	return err
+}
+
+type postgresSubscriptionRepository struct {
+	db *gorm.DB
+}
+
+// NewPostgresSubscriptionRepository создает хранилище подписок в PostgreSQL
+func NewPostgresSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &postgresSubscriptionRepository{db: db}
+}
+
+func (r *postgresSubscriptionRepository) Create(ctx context.Context, sub *models.Subscription) error {
+	return translateError(r.db.WithContext(ctx).Create(sub).Error)
+}
+
+func (r *postgresSubscriptionRepository) Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	return r.get(r.db.WithContext(ctx), id)
+}
+
+func (r *postgresSubscriptionRepository) GetWithDeleted(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	return r.get(r.db.WithContext(ctx).Unscoped(), id)
+}
+
+// get загружает подписку по ID запросом query
+func (r *postgresSubscriptionRepository) get(query *gorm.DB, id uuid.UUID) (*models.Subscription, error) {
+	var sub models.Subscription
+	if err := query.Where("id = ?", id).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (r *postgresSubscriptionRepository) Update(ctx context.Context, sub *models.Subscription) error {
+	expected := sub.Version
+	sub.Version = expected + 1
+
+	result := r.db.WithContext(ctx).Model(sub).
+		Where("version = ?", expected).
+		Select("*").Omit("id", "created_at", "deleted_at").
+		Updates(sub)
+	if result.Error != nil {
+		sub.Version = expected
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		sub.Version = expected
+		return r.missingOrConflict(ctx, sub.ID, false)
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) Delete(ctx context.Context, id uuid.UUID, version int64) error {
+	query := r.db.WithContext(ctx).Where("id = ?", id)
+	if version != 0 {
+		query = query.Where("version = ?", version)
+	}
+
+	result := query.Delete(&models.Subscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.missingOrConflict(ctx, id, false)
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	result := r.db.WithContext(ctx).Unscoped().Model(&models.Subscription{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now().UTC(),
+		})
+	if result.Error != nil {
+		return nil, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.Get(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotDeleted
+	}
+	return r.Get(ctx, id)
+}
+
+func (r *postgresSubscriptionRepository) HardDelete(ctx context.Context, id uuid.UUID, versions []int64) error {
+	query := r.db.WithContext(ctx).Unscoped().Where("id = ?", id)
+	if versions != nil {
+		if len(versions) == 0 {
+			return r.missingOrConflict(ctx, id, true)
+		}
+		query = query.Where("version IN ?", versions)
+	}
+
+	result := query.Delete(&models.Subscription{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.missingOrConflict(ctx, id, true)
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Delete(&models.Subscription{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresSubscriptionRepository) Expire(ctx context.Context, month time.Time, source EventSource) (int64, error) {
+	var expired int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		expired, err = updateSubscriptions(tx, tx.Where("status <> ? AND end_date < ?", models.StatusExpired, month),
+			map[string]interface{}{"status": models.StatusExpired}, source, models.EventExpired,
+			func(sub models.Subscription) models.EventChanges {
+				return statusChanges(sub, models.StatusExpired)
+			})
+		return err
+	})
+	return expired, err
+}
+
+func (r *postgresSubscriptionRepository) EndTrials(ctx context.Context, month time.Time, source EventSource) (int64, error) {
+	var ended int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		ended, err = updateSubscriptions(tx,
+			tx.Where("status = ? AND start_date + make_interval(months => trial_months) <= ?", models.StatusTrial, month),
+			map[string]interface{}{"status": models.StatusActive}, source, models.EventTrialEnded,
+			func(sub models.Subscription) models.EventChanges {
+				return statusChanges(sub, models.StatusActive)
+			})
+		return err
+	})
+	return ended, err
+}
+
+// missingOrConflict определяет, почему запрос с проверкой версии не изменил ни одной строки:
+// подписка удалена или её версия изменилась. С unscoped учитываются и удаленные подписки.
+func (r *postgresSubscriptionRepository) missingOrConflict(ctx context.Context, id uuid.UUID, unscoped bool) error {
+	query := r.db.WithContext(ctx).Model(&models.Subscription{})
+	if unscoped {
+		query = query.Unscoped()
+	}
+
+	var count int64
+	if err := query.Where("id = ?", id).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrNotFound
+	}
+	return ErrVersionConflict
+}
+
+func (r *postgresSubscriptionRepository) List(ctx context.Context, filter ListFilter, page Page) (*ListResult, error) {
+	query := r.filtered(ctx, filter).Session(&gorm.Session{})
+	result := &ListResult{}
+
+	if page.WithTotal {
+		if err := query.Count(&result.Total).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if page.UseCursor {
+		direction := "ASC"
+		if page.Desc {
+			direction = "DESC"
+		}
+		pageQuery := query
+		if page.After != nil {
+			op := ">"
+			if page.Desc {
+				op = "<"
+			}
+			pageQuery = pageQuery.Where("(created_at, id) "+op+" (?, ?)", page.After.CreatedAt, page.After.ID)
+		}
+
+		// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+		order := fmt.Sprintf("created_at %s, id %s", direction, direction)
+		if err := pageQuery.Order(order).Limit(page.Limit + 1).Find(&result.Items).Error; err != nil {
+			return nil, err
+		}
+		if len(result.Items) > page.Limit {
+			result.Items = result.Items[:page.Limit]
+			last := result.Items[len(result.Items)-1]
+			result.NextCursor = &Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+		}
+		return result, nil
+	}
+
+	order, err := orderClause(page.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if err := query.Order(order).Offset(page.Offset).Limit(page.Limit).Find(&result.Items).Error; err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *postgresSubscriptionRepository) Each(ctx context.Context, filter ListFilter, sort []SortField, fn func(sub *models.Subscription) error) error {
+	order, err := orderClause(sort)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.filtered(ctx, filter).Order(order).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	db := r.db.WithContext(ctx)
+	for rows.Next() {
+		var sub models.Subscription
+		if err := db.ScanRows(rows, &sub); err != nil {
+			return err
+		}
+		if err := fn(&sub); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (r *postgresSubscriptionRepository) Aggregate(ctx context.Context, filter AggregateFilter) ([]models.Subscription, error) {
+	query := r.db.WithContext(ctx).Model(&models.Subscription{})
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.ServiceID != nil {
+		query = query.Where("service_id = ?", *filter.ServiceID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+
+	var subscriptions []models.Subscription
+	if err := activeInPeriodQuery(query, filter.From, filter.To).Order("start_date, id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	if len(subscriptions) == 0 {
+		return subscriptions, nil
+	}
+
+	ids := make([]uuid.UUID, len(subscriptions))
+	for i, sub := range subscriptions {
+		ids[i] = sub.ID
+	}
+	var prices []models.SubscriptionPrice
+	if err := r.db.WithContext(ctx).Where("subscription_id IN ?", ids).Order("subscription_id, effective_from").Find(&prices).Error; err != nil {
+		return nil, err
+	}
+	var pauses []models.SubscriptionPause
+	if err := r.db.WithContext(ctx).Where("subscription_id IN ?", ids).Order("subscription_id, paused_from").Find(&pauses).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID][]models.SubscriptionPrice, len(subscriptions))
+	for _, price := range prices {
+		byID[price.SubscriptionID] = append(byID[price.SubscriptionID], price)
+	}
+	pausesByID := make(map[uuid.UUID][]models.SubscriptionPause, len(subscriptions))
+	for _, pause := range pauses {
+		pausesByID[pause.SubscriptionID] = append(pausesByID[pause.SubscriptionID], pause)
+	}
+	for i := range subscriptions {
+		subscriptions[i].Prices = byID[subscriptions[i].ID]
+		subscriptions[i].Pauses = pausesByID[subscriptions[i].ID]
+	}
+	return subscriptions, nil
+}
+
+func (r *postgresSubscriptionRepository) Overlapping(ctx context.Context, sub models.Subscription) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND service_name = ? AND id <> ?", sub.UserID, sub.ServiceName, sub.ID).
+		// Диапазон с включенными границами, как в ограничении ConstraintNoOverlap: месяц окончания входит в период
+		Where("daterange(start_date, end_date, '[]') && daterange(?::date, ?::date, '[]')", sub.StartDate, sub.EndDate).
+		Order("start_date, id").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *postgresSubscriptionRepository) Overlaps(ctx context.Context, filter OverlapFilter) ([]OverlapPair, error) {
+	query := r.db.WithContext(ctx).Table("subscriptions a").
+		Select("a.id AS a, b.id AS b").
+		Joins("JOIN subscriptions b ON b.user_id = a.user_id AND b.service_name = a.service_name AND b.deleted_at IS NULL " +
+			"AND daterange(a.start_date, a.end_date, '[]') && daterange(b.start_date, b.end_date, '[]')").
+		// Каждая пара попадает в результат один раз: первой идет подписка, начавшаяся раньше
+		Where("a.deleted_at IS NULL AND (a.start_date, a.id) < (b.start_date, b.id)")
+	if filter.UserID != nil {
+		query = query.Where("a.user_id = ?", *filter.UserID)
+	}
+	if filter.ServiceID != nil {
+		query = query.Where("a.service_id = ?", *filter.ServiceID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("a.service_name = ?", filter.ServiceName)
+	}
+
+	var ids []struct {
+		A uuid.UUID
+		B uuid.UUID
+	}
+	if err := query.Order("a.user_id, a.service_name, a.start_date, a.id, b.start_date, b.id").Scan(&ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []OverlapPair{}, nil
+	}
+
+	unique := make(map[uuid.UUID]bool, len(ids)*2)
+	for _, pair := range ids {
+		unique[pair.A] = true
+		unique[pair.B] = true
+	}
+	keys := make([]uuid.UUID, 0, len(unique))
+	for id := range unique {
+		keys = append(keys, id)
+	}
+	var subscriptions []models.Subscription
+	if err := r.db.WithContext(ctx).Where("id IN ?", keys).Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Subscription, len(subscriptions))
+	for _, sub := range subscriptions {
+		byID[sub.ID] = sub
+	}
+
+	pairs := make([]OverlapPair, 0, len(ids))
+	for _, pair := range ids {
+		pairs = append(pairs, OverlapPair{A: byID[pair.A], B: byID[pair.B]})
+	}
+	return pairs, nil
+}
+
+func (r *postgresSubscriptionRepository) Prices(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPrice, error) {
+	var prices []models.SubscriptionPrice
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("effective_from").
+		Find(&prices).Error
+	return prices, err
+}
+
+func (r *postgresSubscriptionRepository) SetPrice(ctx context.Context, price *models.SubscriptionPrice) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("subscription_id = ? AND effective_from >= ?", price.SubscriptionID, price.EffectiveFrom).
+			Delete(&models.SubscriptionPrice{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(price).Error
+	})
+}
+
+func (r *postgresSubscriptionRepository) Pauses(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPause, error) {
+	var pauses []models.SubscriptionPause
+	err := r.db.WithContext(ctx).Where("subscription_id = ?", subscriptionID).Order("paused_from").Find(&pauses).Error
+	if err != nil {
+		return nil, err
+	}
+	return pauses, nil
+}
+
+func (r *postgresSubscriptionRepository) SavePause(ctx context.Context, pause *models.SubscriptionPause) error {
+	return r.db.WithContext(ctx).Save(pause).Error
+}
+
+func (r *postgresSubscriptionRepository) AddEvent(ctx context.Context, event *models.SubscriptionEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *postgresSubscriptionRepository) History(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionEvent, error) {
+	var events []models.SubscriptionEvent
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("created_at, id").
+		Find(&events).Error
+	return events, err
+}
+
+func (r *postgresSubscriptionRepository) IdempotencyKey(ctx context.Context, key string, now time.Time) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.WithContext(ctx).Where("key = ? AND expires_at > ?", key, now).Take(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrIdempotencyKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *postgresSubscriptionRepository) SaveIdempotencyKey(ctx context.Context, record *models.IdempotencyKey) error {
+	// Конкурентный запрос с тем же ключом ждет завершения транзакции, вставившей ключ первой
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "idempotency_keys.expires_at <= ?", Vars: []interface{}{record.CreatedAt}}}},
+		DoUpdates: clause.AssignmentColumns([]string{"request_hash", "response", "created_at", "expires_at"}),
+	}).Create(record)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyKeyExists
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *postgresSubscriptionRepository) CreateService(ctx context.Context, svc *models.Service, source EventSource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(svc).Error; err != nil {
+			return err
+		}
+		if err := createAliases(tx, svc); err != nil {
+			return err
+		}
+		_, err := (&postgresSubscriptionRepository{db: tx}).LinkServices(ctx, source)
+		return err
+	})
+}
+
+func (r *postgresSubscriptionRepository) GetService(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	var svc models.Service
+	err := r.db.WithContext(ctx).Where("id = ?", id).Take(&svc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	services := []models.Service{svc}
+	if err := r.loadAliases(ctx, services); err != nil {
+		return nil, err
+	}
+	return &services[0], nil
+}
+
+func (r *postgresSubscriptionRepository) ListServices(ctx context.Context, filter ServiceFilter) ([]models.Service, error) {
+	query := r.db.WithContext(ctx).Model(&models.Service{})
+	if filter.Search != "" {
+		query = query.Where("id IN (SELECT service_id FROM service_aliases WHERE normalized LIKE ?)",
+			"%"+escapeLike(models.NormalizeServiceName(filter.Search))+"%")
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+
+	var services []models.Service
+	if err := query.Order("name, id").Find(&services).Error; err != nil {
+		return nil, err
+	}
+	if err := r.loadAliases(ctx, services); err != nil {
+		return nil, err
+	}
+	return services, nil
+}
+
+func (r *postgresSubscriptionRepository) UpdateService(ctx context.Context, svc *models.Service, source EventSource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(svc).Select("*").Omit("id", "created_at").Updates(svc)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrServiceNotFound
+		}
+
+		if err := tx.Where("service_id = ?", svc.ID).Delete(&models.ServiceAlias{}).Error; err != nil {
+			return err
+		}
+		if err := createAliases(tx, svc); err != nil {
+			return err
+		}
+
+		// Название подписок повторяет название сервиса каталога
+		_, err := updateSubscriptions(tx, tx.Unscoped().Where("service_id = ? AND service_name <> ?", svc.ID, svc.Name),
+			map[string]interface{}{"service_name": svc.Name}, source, models.EventUpdated,
+			func(sub models.Subscription) models.EventChanges {
+				return serviceChanges(sub, sub.ServiceID, svc.Name)
+			})
+		if err != nil {
+			return err
+		}
+		repo := &postgresSubscriptionRepository{db: tx}
+		if _, err := repo.LinkServices(ctx, source); err != nil {
+			return err
+		}
+
+		// Дата создания не обновляется, поэтому сервис перечитывается целиком
+		if err := tx.Where("id = ?", svc.ID).Take(svc).Error; err != nil {
+			return err
+		}
+		services := []models.Service{*svc}
+		if err := repo.loadAliases(ctx, services); err != nil {
+			return err
+		}
+		*svc = services[0]
+		return nil
+	})
+}
+
+func (r *postgresSubscriptionRepository) DeleteService(ctx context.Context, id uuid.UUID, source EventSource) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := updateSubscriptions(tx, tx.Unscoped().Where("service_id = ?", id),
+			map[string]interface{}{"service_id": nil}, source, models.EventUpdated,
+			func(sub models.Subscription) models.EventChanges {
+				return serviceChanges(sub, nil, sub.ServiceName)
+			})
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("service_id = ?", id).Delete(&models.ServiceAlias{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ?", id).Delete(&models.Service{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrServiceNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresSubscriptionRepository) ServiceByName(ctx context.Context, name string) (*models.Service, error) {
+	var svc models.Service
+	err := r.db.WithContext(ctx).
+		Where("id = (SELECT service_id FROM service_aliases WHERE normalized = ?)", models.NormalizeServiceName(name)).
+		Take(&svc).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrServiceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &svc, nil
+}
+
+func (r *postgresSubscriptionRepository) LinkServices(ctx context.Context, source EventSource) (int64, error) {
+	var names []string
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Subscription{}).
+		Where("service_id IS NULL").
+		Distinct().Pluck("service_name", &names).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// Названия нормализуются в Go, как и псевдонимы каталога
+	var linked int64
+	for _, name := range names {
+		svc, err := r.ServiceByName(ctx, name)
+		if errors.Is(err, ErrServiceNotFound) {
+			continue
+		}
+		if err != nil {
+			return linked, err
+		}
+
+		// Подписки, которые после переименования пересеклись бы с другими, остаются без ссылки на каталог.
+		// Каждое название связывается в своей точке сохранения, чтобы такая ошибка не прерывала транзакцию
+		err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			count, err := updateSubscriptions(tx, tx.Unscoped().Where("service_id IS NULL AND service_name = ?", name),
+				map[string]interface{}{"service_id": svc.ID, "service_name": svc.Name}, source, models.EventUpdated,
+				func(sub models.Subscription) models.EventChanges {
+					return serviceChanges(sub, &svc.ID, svc.Name)
+				})
+			linked += count
+			return err
+		})
+		if err != nil && !errors.Is(err, ErrOverlap) {
+			return linked, err
+		}
+	}
+	return linked, nil
+}
+
+// updateSubscriptions сохраняет values в подписках, выбранных запросом query, увеличивает их версию
+// и записывает в журнал событие action с изменениями, которые changes возвращает по подписке до изменения.
+// Выбранные подписки блокируются до конца транзакции tx, поэтому журнал совпадает с сохраненными данными
+func updateSubscriptions(tx, query *gorm.DB, values map[string]interface{}, source EventSource, action string,
+	changes func(sub models.Subscription) models.EventChanges) (int64, error) {
+	var subs []models.Subscription
+	if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Find(&subs).Error; err != nil {
+		return 0, err
+	}
+	if len(subs) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uuid.UUID, len(subs))
+	events := make([]models.SubscriptionEvent, len(subs))
+	for i, sub := range subs {
+		ids[i] = sub.ID
+		events[i] = source.event(sub.ID, action, changes(sub))
+	}
+
+	updates := map[string]interface{}{
+		"version":    gorm.Expr("version + 1"),
+		"updated_at": time.Now().UTC(),
+	}
+	for column, value := range values {
+		updates[column] = value
+	}
+	result := tx.Unscoped().Model(&models.Subscription{}).Where("id IN ?", ids).Updates(updates)
+	if result.Error != nil {
+		return 0, translateError(result.Error)
+	}
+	if err := tx.CreateInBatches(events, 500).Error; err != nil {
+		return 0, err
+	}
+	return result.RowsAffected, nil
+}
+
+// createAliases сохраняет название и псевдонимы сервиса
+func createAliases(tx *gorm.DB, svc *models.Service) error {
+	aliases := make([]models.ServiceAlias, 0, len(svc.Aliases)+1)
+	for _, alias := range append([]string{svc.Name}, svc.Aliases...) {
+		aliases = append(aliases, models.ServiceAlias{
+			Normalized: models.NormalizeServiceName(alias),
+			ServiceID:  svc.ID,
+			Alias:      alias,
+		})
+	}
+	return translateError(tx.Create(&aliases).Error)
+}
+
+// loadAliases заполняет псевдонимы сервисов, кроме их собственных названий
+func (r *postgresSubscriptionRepository) loadAliases(ctx context.Context, services []models.Service) error {
+	if len(services) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(services))
+	for i, svc := range services {
+		ids[i] = svc.ID
+	}
+
+	var aliases []models.ServiceAlias
+	if err := r.db.WithContext(ctx).Where("service_id IN ?", ids).Order("alias").Find(&aliases).Error; err != nil {
+		return err
+	}
+	byID := make(map[uuid.UUID][]string, len(services))
+	for _, alias := range aliases {
+		byID[alias.ServiceID] = append(byID[alias.ServiceID], alias.Alias)
+	}
+	for i := range services {
+		services[i].Aliases = []string{}
+		for _, alias := range byID[services[i].ID] {
+			if alias != services[i].Name {
+				services[i].Aliases = append(services[i].Aliases, alias)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) CreateUser(ctx context.Context, user *models.User) error {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserExists
+	}
+	return nil
+}
+
+func (r *postgresSubscriptionRepository) GetUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Where("id = ?", id).Take(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *postgresSubscriptionRepository) UpdateUser(ctx context.Context, user *models.User) error {
+	result := r.db.WithContext(ctx).Model(user).Select("name", "email", "updated_at").Updates(user)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return r.db.WithContext(ctx).Where("id = ?", user.ID).Take(user).Error
+}
+
+func (r *postgresSubscriptionRepository) EnsureUser(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&models.User{ID: id}).Error
+}
+
+func (r *postgresSubscriptionRepository) InTx(ctx context.Context, fn func(repo SubscriptionRepository) error) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&postgresSubscriptionRepository{db: tx})
+	})
+}
+
+// filtered строит запрос подписок с фильтрами списка
+func (r *postgresSubscriptionRepository) filtered(ctx context.Context, filter ListFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&models.Subscription{})
+
+	if filter.Deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.ServiceID != nil {
+		query = query.Where("service_id = ?", *filter.ServiceID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("service_name = ?", filter.ServiceName)
+	}
+	if filter.Search != "" {
+		query = query.Where("service_name ILIKE ?", "%"+escapeLike(filter.Search)+"%")
+	}
+	if filter.Currency != "" {
+		query = query.Where("currency = ?", filter.Currency)
+	}
+	if filter.MinPrice != nil {
+		query = query.Where("price >= ?", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		query = query.Where("price <= ?", *filter.MaxPrice)
+	}
+	if filter.ActiveAt != nil {
+		query = activeInPeriodQuery(query, *filter.ActiveAt, *filter.ActiveAt)
+	}
+	if filter.HasEndDate != nil {
+		if *filter.HasEndDate {
+			query = query.Where("end_date IS NOT NULL")
+		} else {
+			query = query.Where("end_date IS NULL")
+		}
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	return query
+}
+
+// activeInPeriodQuery оставляет подписки, активные хотя бы в одном месяце периода [from, to]
+func activeInPeriodQuery(query *gorm.DB, from, to time.Time) *gorm.DB {
+	return query.Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", to.AddDate(0, 1, -1), from)
+}
+
+// orderClause строит выражение ORDER BY. id всегда добавляется последним,
+// чтобы порядок был детерминированным.
+func orderClause(sort []SortField) (string, error) {
+	clauses := make([]string, 0, len(sort)+1)
+	for _, s := range sort {
+		if !IsSortable(s.Field) {
+			return "", fmt.Errorf("invalid sort field %q", s.Field)
+		}
+		if s.Desc {
+			clauses = append(clauses, s.Field+" DESC")
+		} else {
+			clauses = append(clauses, s.Field+" ASC")
+		}
+	}
+	clauses = append(clauses, "id ASC")
+	return strings.Join(clauses, ", "), nil
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
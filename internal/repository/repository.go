@@ -0,0 +1,299 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"subscription-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotFound возвращается, если подписка не найдена
+	ErrNotFound = errors.New("subscription not found")
+	// ErrInvalidDates возвращается, если дата окончания подписки раньше даты начала
+	ErrInvalidDates = errors.New("end_date is before start_date")
+	// ErrVersionConflict возвращается, если версия подписки в хранилище не совпадает с ожидаемой
+	ErrVersionConflict = errors.New("subscription version mismatch")
+	// ErrNotDeleted возвращается при попытке восстановить подписку, которая не удалена
+	ErrNotDeleted = errors.New("subscription is not deleted")
+	// ErrOverlap возвращается, если период подписки пересекается с другой подпиской того же пользователя на тот же сервис
+	ErrOverlap = errors.New("subscription overlaps with another subscription")
+	// ErrServiceNotFound возвращается, если сервиса нет в каталоге
+	ErrServiceNotFound = errors.New("service not found")
+	// ErrServiceAliasExists возвращается, если название или псевдоним сервиса уже занят другим сервисом каталога
+	ErrServiceAliasExists = errors.New("service alias already exists")
+	// ErrUserNotFound возвращается, если пользователя нет
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserExists возвращается при создании пользователя с уже занятым ID
+	ErrUserExists = errors.New("user already exists")
+	// ErrIdempotencyKeyNotFound возвращается, если ключа идемпотентности нет или срок его хранения истек
+	ErrIdempotencyKeyNotFound = errors.New("idempotency key not found")
+	// ErrIdempotencyKeyExists возвращается при сохранении ключа идемпотентности, который уже занят
+	ErrIdempotencyKeyExists = errors.New("idempotency key already exists")
+)
+
+// SubscriptionRepository описывает хранилище подписок
+type SubscriptionRepository interface {
+	// Сервисы каталога и пользователи нужны подпискам для связи по названию
+	// и учета владельцев в той же транзакции
+	ServiceRepository
+	UserRepository
+
+	Create(ctx context.Context, sub *models.Subscription) error
+	Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	// GetWithDeleted возвращает подписку по ID, в том числе находящуюся в корзине
+	GetWithDeleted(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	// Update сохраняет подписку, если её версия в хранилище равна sub.Version, и увеличивает версию
+	Update(ctx context.Context, sub *models.Subscription) error
+	// Delete удаляет подписку. Если version не 0, подписка удаляется только в этой версии
+	Delete(ctx context.Context, id uuid.UUID, version int64) error
+	// Restore восстанавливает удаленную подписку и увеличивает её версию
+	Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error)
+	// HardDelete безвозвратно удаляет подписку, в том числе уже удаленную.
+	// Если versions не nil, подписка удаляется, только если её версия входит в список
+	HardDelete(ctx context.Context, id uuid.UUID, versions []int64) error
+	// Purge безвозвратно удаляет подписки, удаленные раньше before, и возвращает их количество
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	// Expire переводит в статус expired действующие подписки, закончившиеся раньше месяца month,
+	// записывает переход в журнал от имени source и возвращает количество подписок
+	Expire(ctx context.Context, month time.Time, source EventSource) (int64, error)
+	// EndTrials переводит в статус active пробные подписки, пробный период которых закончился
+	// не позже месяца month, записывает переход в журнал от имени source и возвращает количество подписок
+	EndTrials(ctx context.Context, month time.Time, source EventSource) (int64, error)
+	List(ctx context.Context, filter ListFilter, page Page) (*ListResult, error)
+	// Each вызывает fn для каждой подписки, подходящей под фильтр, в порядке sort, не загружая
+	// весь список в память. Ошибка fn прекращает обход и возвращается из Each
+	Each(ctx context.Context, filter ListFilter, sort []SortField, fn func(sub *models.Subscription) error) error
+	// Aggregate возвращает подписки, активные хотя бы в одном месяце периода фильтра,
+	// вместе с их шкалами цен и приостановками
+	Aggregate(ctx context.Context, filter AggregateFilter) ([]models.Subscription, error)
+
+	// Overlapping возвращает действующие подписки того же пользователя на тот же сервис,
+	// период которых пересекается с периодом sub. Сама sub в результат не входит
+	Overlapping(ctx context.Context, sub models.Subscription) ([]models.Subscription, error)
+	// Overlaps возвращает все пары пересекающихся действующих подписок, подходящих под фильтр
+	Overlaps(ctx context.Context, filter OverlapFilter) ([]OverlapPair, error)
+
+	// Prices возвращает шкалу цен подписки по возрастанию EffectiveFrom
+	Prices(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPrice, error)
+	// SetPrice устанавливает цену подписки с месяца price.EffectiveFrom.
+	// Изменения цены, действующие с этого месяца и позже, заменяются
+	SetPrice(ctx context.Context, price *models.SubscriptionPrice) error
+
+	// Pauses возвращает приостановки подписки по возрастанию PausedFrom
+	Pauses(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionPause, error)
+	// SavePause сохраняет новую приостановку или изменения существующей
+	SavePause(ctx context.Context, pause *models.SubscriptionPause) error
+
+	// AddEvent записывает событие в журнал изменений подписок
+	AddEvent(ctx context.Context, event *models.SubscriptionEvent) error
+	// History возвращает журнал изменений подписки в порядке записи
+	History(ctx context.Context, subscriptionID uuid.UUID) ([]models.SubscriptionEvent, error)
+
+	// IdempotencyKey возвращает ключ идемпотентности, срок хранения которого на момент now не истек
+	IdempotencyKey(ctx context.Context, key string, now time.Time) (*models.IdempotencyKey, error)
+	// SaveIdempotencyKey сохраняет ключ идемпотентности. Истекший ключ с тем же именем заменяется,
+	// действующий - не изменяется, и возвращается ErrIdempotencyKeyExists
+	SaveIdempotencyKey(ctx context.Context, record *models.IdempotencyKey) error
+	// PurgeIdempotencyKeys удаляет ключи идемпотентности, срок хранения которых истек к now
+	PurgeIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+
+	// InTx выполняет fn в транзакции: изменения, сделанные через переданное хранилище,
+	// сохраняются, только если fn не вернула ошибку
+	InTx(ctx context.Context, fn func(repo SubscriptionRepository) error) error
+}
+
+// ServiceRepository описывает хранилище каталога сервисов. Изменения каталога, затрагивающие подписки,
+// записываются в журнал каждой подписки от имени source в той же транзакции
+type ServiceRepository interface {
+	// CreateService добавляет сервис в каталог вместе с названием и псевдонимами
+	// и связывает с ним подписки с совпадающим названием
+	CreateService(ctx context.Context, svc *models.Service, source EventSource) error
+	// GetService возвращает сервис каталога с псевдонимами
+	GetService(ctx context.Context, id uuid.UUID) (*models.Service, error)
+	// ListServices возвращает сервисы каталога по названию
+	ListServices(ctx context.Context, filter ServiceFilter) ([]models.Service, error)
+	// UpdateService сохраняет сервис и заменяет его псевдонимы. Подписки на сервис получают новое название,
+	// подписки с названием из новых псевдонимов связываются с сервисом
+	UpdateService(ctx context.Context, svc *models.Service, source EventSource) error
+	// DeleteService удаляет сервис из каталога. Подписки на сервис сохраняют название, но теряют ссылку на него
+	DeleteService(ctx context.Context, id uuid.UUID, source EventSource) error
+	// ServiceByName находит сервис каталога по названию или псевдониму без учета регистра и лишних пробелов
+	ServiceByName(ctx context.Context, name string) (*models.Service, error)
+	// LinkServices связывает подписки без ссылки на каталог с сервисами, название или псевдоним
+	// которых совпадает с названием подписки, и возвращает количество связанных подписок
+	LinkServices(ctx context.Context, source EventSource) (int64, error)
+}
+
+// UserRepository описывает хранилище пользователей
+type UserRepository interface {
+	// CreateUser добавляет пользователя
+	CreateUser(ctx context.Context, user *models.User) error
+	// GetUser возвращает пользователя по ID
+	GetUser(ctx context.Context, id uuid.UUID) (*models.User, error)
+	// UpdateUser сохраняет имя и email пользователя
+	UpdateUser(ctx context.Context, user *models.User) error
+	// EnsureUser создает пользователя с пустыми данными, если его ещё нет
+	EnsureUser(ctx context.Context, id uuid.UUID) error
+}
+
+// SystemActor - автор изменений, которые сервис выполняет сам: миграций и фоновых задач
+const SystemActor = "system"
+
+// EventSource - автор изменений подписок, которые хранилище записывает в журнал само
+type EventSource struct {
+	Actor     string
+	RequestID string
+}
+
+// event возвращает событие журнала об изменении подписки от имени источника
+func (s EventSource) event(subscriptionID uuid.UUID, action string, changes models.EventChanges) models.SubscriptionEvent {
+	return models.SubscriptionEvent{
+		SubscriptionID: subscriptionID,
+		Action:         action,
+		Actor:          s.Actor,
+		RequestID:      s.RequestID,
+		Changes:        changes,
+	}
+}
+
+// serviceChanges возвращает изменения подписки sub при переходе к сервису serviceID с названием name.
+// Значения записываются так же, как в JSON-представлении подписки
+func serviceChanges(sub models.Subscription, serviceID *uuid.UUID, name string) models.EventChanges {
+	changes := models.EventChanges{}
+	if before, after := optionalID(sub.ServiceID), optionalID(serviceID); before != after {
+		changes["service_id"] = models.FieldChange{Before: before, After: after}
+	}
+	if sub.ServiceName != name {
+		changes["service_name"] = models.FieldChange{Before: sub.ServiceName, After: name}
+	}
+	return changes
+}
+
+// statusChanges возвращает изменения подписки sub при переходе в статус status
+func statusChanges(sub models.Subscription, status string) models.EventChanges {
+	return models.EventChanges{"status": {Before: sub.Status, After: status}}
+}
+
+// optionalID возвращает необязательный ID в виде строки или nil
+func optionalID(id *uuid.UUID) interface{} {
+	if id == nil {
+		return nil
+	}
+	return id.String()
+}
+
+// ListFilter задает фильтры списка подписок. Пустые поля не фильтруют.
+type ListFilter struct {
+	UserID      *uuid.UUID
+	ServiceID   *uuid.UUID
+	ServiceName string
+	// Search - подстрока названия сервиса без учета регистра
+	Search     string
+	Currency   string
+	MinPrice   *int
+	MaxPrice   *int
+	ActiveAt   *time.Time
+	HasEndDate *bool
+	Status     string
+	// Deleted - вернуть удаленные подписки вместо действующих
+	Deleted bool
+}
+
+// Поля, по которым разрешена сортировка списка подписок
+var SortableFields = []string{"service_name", "price", "start_date", "end_date", "created_at", "updated_at"}
+
+// SortField задает сортировку по одному полю
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor указывает на последнюю запись страницы при обходе по (created_at, id)
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Page задает страницу списка.
+// Если UseCursor установлен, записи обходятся по (created_at, id) в направлении Desc
+// начиная после After, иначе используются Sort и Offset.
+type Page struct {
+	Limit     int
+	Offset    int
+	Sort      []SortField
+	UseCursor bool
+	After     *Cursor
+	Desc      bool
+	WithTotal bool
+}
+
+// ListResult содержит страницу списка подписок
+type ListResult struct {
+	Items []models.Subscription
+	// Total заполняется, только если запрошен Page.WithTotal
+	Total int64
+	// NextCursor указывает на последнюю запись страницы, если в режиме cursor есть следующая страница
+	NextCursor *Cursor
+}
+
+// AggregateFilter задает подписки, участвующие в расчете стоимости за период [From, To]
+type AggregateFilter struct {
+	UserID      *uuid.UUID
+	ServiceID   *uuid.UUID
+	ServiceName string
+	From        time.Time
+	To          time.Time
+}
+
+// OverlapFilter задает подписки, среди которых ищутся пересечения. Пустые поля не фильтруют.
+type OverlapFilter struct {
+	UserID      *uuid.UUID
+	ServiceID   *uuid.UUID
+	ServiceName string
+}
+
+// ServiceFilter задает фильтры каталога сервисов. Пустые поля не фильтруют.
+type ServiceFilter struct {
+	// Search - подстрока названия или псевдонима без учета регистра
+	Search   string
+	Category string
+}
+
+// OverlapPair - две пересекающиеся подписки одного пользователя на один сервис
+type OverlapPair struct {
+	A models.Subscription
+	B models.Subscription
+}
+
+// periodsOverlap сообщает, пересекаются ли периоды подписок. Месяц окончания входит в период,
+// подписка без даты окончания бессрочна
+func periodsOverlap(a, b models.Subscription) bool {
+	if a.EndDate != nil && a.EndDate.Before(b.StartDate) {
+		return false
+	}
+	return b.EndDate == nil || !b.EndDate.Before(a.StartDate)
+}
+
+// activeInPeriod сообщает, активна ли подписка хотя бы в одном месяце периода [from, to].
+// Подписка активна в периоде, если она началась до конца периода и не закончилась до начала периода.
+func activeInPeriod(sub models.Subscription, from, to time.Time) bool {
+	endOfPeriod := to.AddDate(0, 1, -1)
+	if sub.StartDate.After(endOfPeriod) {
+		return false
+	}
+	return sub.EndDate == nil || !sub.EndDate.Before(from)
+}
+
+// IsSortable сообщает, разрешена ли сортировка по полю
+func IsSortable(field string) bool {
+	for _, f := range SortableFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package retention
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"subscription-service/internal/config"
+	"subscription-service/internal/service"
+)
+
+// Run периодически безвозвратно удаляет подписки, находящиеся в корзине дольше
+// cfg.DeletedDays дней (если очистка корзины включена), и ключи идемпотентности с истекшим
+// сроком хранения, а также переводит подписки с закончившимся пробным периодом в статус active,
+// а закончившиеся подписки - в статус expired. Работает до отмены ctx.
+func Run(ctx context.Context, subscriptions *service.SubscriptionService, cfg config.RetentionConfig) {
+	var retention time.Duration
+	if cfg.DeletedDays > 0 {
+		retention = time.Duration(cfg.DeletedDays) * 24 * time.Hour
+		log.Printf("Retention purge enabled: deleted subscriptions are kept for %d days, checked every %s", cfg.DeletedDays, cfg.PurgeInterval)
+	} else {
+		log.Println("Retention purge disabled")
+	}
+
+	ticker := time.NewTicker(cfg.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if retention > 0 {
+			purge(ctx, subscriptions, retention)
+		}
+		purgeIdempotencyKeys(ctx, subscriptions)
+		endTrials(ctx, subscriptions)
+		expire(ctx, subscriptions)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge выполняет один проход очистки
+func purge(ctx context.Context, subscriptions *service.SubscriptionService, retention time.Duration) {
+	purged, err := subscriptions.PurgeDeleted(ctx, retention)
+	if err != nil {
+		log.Printf("Error purging deleted subscriptions: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d deleted subscriptions", purged)
+	}
+}
+
+// expire переводит закончившиеся подписки в статус expired
+func expire(ctx context.Context, subscriptions *service.SubscriptionService) {
+	expired, err := subscriptions.ExpireEnded(ctx)
+	if err != nil {
+		log.Printf("Error expiring ended subscriptions: %v", err)
+		return
+	}
+	if expired > 0 {
+		log.Printf("Expired %d ended subscriptions", expired)
+	}
+}
+
+// endTrials переводит подписки с закончившимся пробным периодом в статус active
+func endTrials(ctx context.Context, subscriptions *service.SubscriptionService) {
+	ended, err := subscriptions.EndTrials(ctx)
+	if err != nil {
+		log.Printf("Error ending subscription trials: %v", err)
+		return
+	}
+	if ended > 0 {
+		log.Printf("Ended trials of %d subscriptions", ended)
+	}
+}
+
+// purgeIdempotencyKeys удаляет истекшие ключи идемпотентности
+func purgeIdempotencyKeys(ctx context.Context, subscriptions *service.SubscriptionService) {
+	purged, err := subscriptions.PurgeIdempotencyKeys(ctx)
+	if err != nil {
+		log.Printf("Error purging expired idempotency keys: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d expired idempotency keys", purged)
+	}
+}
@@ -8,7 +8,7 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRouter(subscriptionHandler *handlers.SubscriptionHandler) *gin.Engine {
+func SetupRouter(subscriptionHandler *handlers.SubscriptionHandler, serviceHandler *handlers.ServiceHandler, userHandler *handlers.UserHandler) *gin.Engine {
 	r := gin.Default()
 
 	// Swagger документация
@@ -16,16 +16,51 @@ func SetupRouter(subscriptionHandler *handlers.SubscriptionHandler) *gin.Engine
 
 	// API v1
 	v1 := r.Group("/api/v1")
+	v1.Use(handlers.AuditContext())
 	{
 		// CRUDL операции для подписок
 		subscriptions := v1.Group("/subscriptions")
 		{
 			subscriptions.POST("", subscriptionHandler.CreateSubscription)
 			subscriptions.GET("", subscriptionHandler.ListSubscriptions)
+			subscriptions.POST("/batch", subscriptionHandler.BatchSubscriptions)
+			subscriptions.POST("/import", subscriptionHandler.ImportSubscriptions)
+			subscriptions.GET("/export", subscriptionHandler.ExportSubscriptions)
 			subscriptions.GET("/:id", subscriptionHandler.GetSubscription)
 			subscriptions.PUT("/:id", subscriptionHandler.UpdateSubscription)
+			subscriptions.PATCH("/:id", subscriptionHandler.PatchSubscription)
 			subscriptions.DELETE("/:id", subscriptionHandler.DeleteSubscription)
 			subscriptions.GET("/total-cost", subscriptionHandler.CalculateTotalCost)
+			subscriptions.GET("/cost-series", subscriptionHandler.CostSeries)
+			subscriptions.GET("/deleted", subscriptionHandler.ListDeletedSubscriptions)
+			subscriptions.GET("/overlaps", subscriptionHandler.SubscriptionOverlaps)
+			subscriptions.GET("/trials", subscriptionHandler.EndingTrials)
+			subscriptions.GET("/upcoming", subscriptionHandler.UpcomingCharges)
+			subscriptions.POST("/:id/restore", subscriptionHandler.RestoreSubscription)
+			subscriptions.POST("/:id/pause", subscriptionHandler.PauseSubscription)
+			subscriptions.POST("/:id/resume", subscriptionHandler.ResumeSubscription)
+			subscriptions.POST("/:id/cancel", subscriptionHandler.CancelSubscription)
+			subscriptions.GET("/:id/history", subscriptionHandler.SubscriptionHistory)
+			subscriptions.GET("/:id/prices", subscriptionHandler.SubscriptionPrices)
+		}
+
+		// Каталог сервисов
+		services := v1.Group("/services")
+		{
+			services.POST("", serviceHandler.CreateService)
+			services.GET("", serviceHandler.ListServices)
+			services.GET("/:id", serviceHandler.GetService)
+			services.PUT("/:id", serviceHandler.UpdateService)
+			services.DELETE("/:id", serviceHandler.DeleteService)
+		}
+
+		// Пользователи
+		users := v1.Group("/users")
+		{
+			users.POST("", userHandler.CreateUser)
+			users.GET("/:id", userHandler.GetUser)
+			users.PUT("/:id", userHandler.UpdateUser)
+			users.GET("/:id/summary", userHandler.UserSummary)
 		}
 	}
 
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// anonymousActor - автор изменений, если запрос не указал его
+const anonymousActor = "anonymous"
+
+// Поля подписки, которые не попадают в журнал изменений: они меняются при каждой записи,
+// вычисляются или не относятся к данным подписки
+var auditIgnoredFields = map[string]bool{
+	"id":               true,
+	"version":          true,
+	"created_at":       true,
+	"updated_at":       true,
+	"next_charge_date": true,
+}
+
+// AuditInfo описывает источник изменения для журнала
+type AuditInfo struct {
+	Actor     string
+	RequestID string
+}
+
+type auditInfoKey struct{}
+
+// WithAuditInfo возвращает контекст, изменения в котором записываются в журнал от имени info
+func WithAuditInfo(ctx context.Context, info AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// auditInfoFrom возвращает источник изменения из контекста
+func auditInfoFrom(ctx context.Context) AuditInfo {
+	info, _ := ctx.Value(auditInfoKey{}).(AuditInfo)
+	if info.Actor == "" {
+		info.Actor = anonymousActor
+	}
+	return info
+}
+
+// eventSource возвращает источник изменения из контекста для изменений, которые хранилище записывает в журнал само
+func eventSource(ctx context.Context) repository.EventSource {
+	info := auditInfoFrom(ctx)
+	return repository.EventSource{Actor: info.Actor, RequestID: info.RequestID}
+}
+
+// subscriptionFields возвращает поля подписки в JSON-представлении. nil дает пустой набор.
+func subscriptionFields(sub *models.Subscription) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if sub == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for field := range auditIgnoredFields {
+		delete(fields, field)
+	}
+	return fields, nil
+}
+
+// diffSubscriptions возвращает поля, различающиеся в before и after.
+// nil означает отсутствие подписки: до создания или после удаления.
+func diffSubscriptions(before, after *models.Subscription) (models.EventChanges, error) {
+	beforeFields, err := subscriptionFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := subscriptionFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.EventChanges{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = models.FieldChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok {
+			changes[field] = models.FieldChange{Before: nil, After: value}
+		}
+	}
+	return changes, nil
+}
+
+// record записывает изменение подписки в журнал через repo
+func record(ctx context.Context, repo repository.SubscriptionRepository, action string, id uuid.UUID, before, after *models.Subscription) error {
+	changes, err := diffSubscriptions(before, after)
+	if err != nil {
+		return err
+	}
+
+	info := auditInfoFrom(ctx)
+	return repo.AddEvent(ctx, &models.SubscriptionEvent{
+		SubscriptionID: id,
+		Action:         action,
+		Actor:          info.Actor,
+		RequestID:      info.RequestID,
+		Changes:        changes,
+	})
+}
+
+// History возвращает журнал изменений подписки, в том числе удаленной
+func (s *SubscriptionService) History(ctx context.Context, id uuid.UUID) ([]models.SubscriptionEvent, error) {
+	events, err := s.repo.History(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		if _, err := s.repo.GetWithDeleted(ctx, id); err != nil {
+			return nil, mapRepoError(err)
+		}
+		events = []models.SubscriptionEvent{}
+	}
+	return events, nil
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Операции пакетного запроса
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchOperations - максимальное количество операций в пакетном запросе
+const MaxBatchOperations = 1000
+
+var (
+	// errBatchAborted прерывает транзакцию атомарного пакета после ошибки операции
+	errBatchAborted = errors.New("batch aborted")
+	// errBatchDryRun откатывает транзакцию пакета, выполненного для проверки
+	errBatchDryRun = errors.New("batch dry run")
+)
+
+// BatchOperation - операция пакетного запроса.
+// Для create используется Create, для update - Patch (JSON Merge Patch), для update и delete - ID и IfMatch.
+type BatchOperation struct {
+	Op      string
+	ID      uuid.UUID
+	IfMatch VersionMatch
+	Create  CreateInput
+	Patch   PatchInput
+	// Err - ошибка разбора операции: такая операция не выполняется и считается неуспешной
+	Err error
+}
+
+// BatchItemResult - результат одной операции пакета
+type BatchItemResult struct {
+	Op           string
+	ID           uuid.UUID
+	Subscription *models.Subscription
+	Err          error
+	// Aborted - операция не выполнена или отменена из-за ошибки другой операции атомарного пакета
+	Aborted bool
+}
+
+// BatchResult - результат пакетного запроса
+type BatchResult struct {
+	Atomic bool
+	// Committed - успешные операции сохранены. В атомарном пакете false, если хотя бы одна операция не выполнена
+	Committed bool
+	Items     []BatchItemResult
+}
+
+// Batch выполняет операции в одной транзакции в порядке следования.
+// В атомарном режиме ошибка любой операции отменяет весь пакет, иначе
+// каждая операция выполняется в своей точке сохранения и отменяется только она.
+func (s *SubscriptionService) Batch(ctx context.Context, ops []BatchOperation, atomic bool) (*BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, NewValidationError("operations", "must not be empty")
+	}
+	if len(ops) > MaxBatchOperations {
+		return nil, NewValidationError("operations", fmt.Sprintf("must contain at most %d operations", MaxBatchOperations))
+	}
+	return s.runBatch(ctx, ops, atomic, false)
+}
+
+// runBatch выполняет операции пакета. С dryRun операции выполняются, но транзакция откатывается.
+func (s *SubscriptionService) runBatch(ctx context.Context, ops []BatchOperation, atomic, dryRun bool) (*BatchResult, error) {
+	result := &BatchResult{Atomic: atomic, Items: make([]BatchItemResult, len(ops))}
+	for i, op := range ops {
+		result.Items[i] = BatchItemResult{Op: op.Op, ID: op.ID}
+	}
+
+	err := s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		for i, op := range ops {
+			item := &result.Items[i]
+			run := func(repo repository.SubscriptionRepository) error {
+				subscription, err := s.batchOperation(ctx, repo, op)
+				if err != nil {
+					return err
+				}
+				item.Subscription = subscription
+				if subscription != nil {
+					item.ID = subscription.ID
+				}
+				return nil
+			}
+
+			if atomic {
+				if err := run(repo); err != nil {
+					item.Err = err
+					return errBatchAborted
+				}
+				continue
+			}
+			if err := repo.InTx(ctx, run); err != nil {
+				item.Err = err
+			}
+		}
+		if dryRun {
+			return errBatchDryRun
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, errBatchAborted):
+		for i := range result.Items {
+			if item := &result.Items[i]; item.Err == nil {
+				item.Aborted = true
+				item.ID = ops[i].ID
+				item.Subscription = nil
+			}
+		}
+	case errors.Is(err, errBatchDryRun):
+		// Операции выполнены для проверки, изменения отменены
+	case err != nil:
+		return nil, err
+	default:
+		result.Committed = true
+	}
+	return result, nil
+}
+
+// batchOperation выполняет одну операцию пакета через repo
+func (s *SubscriptionService) batchOperation(ctx context.Context, repo repository.SubscriptionRepository, op BatchOperation) (*models.Subscription, error) {
+	if op.Err != nil {
+		return nil, op.Err
+	}
+
+	switch op.Op {
+	case BatchCreate:
+		return s.create(ctx, repo, op.Create)
+	case BatchUpdate:
+		return s.patch(ctx, repo, op.ID, op.Patch, op.IfMatch)
+	case BatchDelete:
+		return nil, s.delete(ctx, repo, op.ID, op.IfMatch)
+	default:
+		return nil, NewValidationError("op", "must be one of create, update, delete")
+	}
+}
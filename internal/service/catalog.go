@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// CatalogService содержит бизнес-логику каталога сервисов
+type CatalogService struct {
+	repo repository.ServiceRepository
+}
+
+func NewCatalogService(repo repository.ServiceRepository) *CatalogService {
+	return &CatalogService{repo: repo}
+}
+
+// ServiceInput содержит данные для создания или замены сервиса каталога
+type ServiceInput struct {
+	Name         string
+	Aliases      []string
+	Category     string
+	DefaultPrice *int
+	VendorURL    string
+}
+
+// ServiceListInput задает фильтры каталога сервисов
+type ServiceListInput struct {
+	Search   string
+	Category string
+}
+
+// Create добавляет сервис в каталог и связывает с ним подписки с совпадающим названием
+func (s *CatalogService) Create(ctx context.Context, in ServiceInput) (*models.Service, error) {
+	var svc models.Service
+	if err := applyService(&svc, in); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateService(ctx, &svc, eventSource(ctx)); err != nil {
+		return nil, mapServiceError(err)
+	}
+	return &svc, nil
+}
+
+// Get возвращает сервис каталога по ID
+func (s *CatalogService) Get(ctx context.Context, id uuid.UUID) (*models.Service, error) {
+	svc, err := s.repo.GetService(ctx, id)
+	if err != nil {
+		return nil, mapServiceError(err)
+	}
+	return svc, nil
+}
+
+// List возвращает сервисы каталога
+func (s *CatalogService) List(ctx context.Context, in ServiceListInput) ([]models.Service, error) {
+	return s.repo.ListServices(ctx, repository.ServiceFilter{
+		Search:   strings.TrimSpace(in.Search),
+		Category: strings.TrimSpace(in.Category),
+	})
+}
+
+// Update заменяет данные сервиса каталога. Подписки на сервис получают новое название,
+// подписки с названием из новых псевдонимов связываются с сервисом
+func (s *CatalogService) Update(ctx context.Context, id uuid.UUID, in ServiceInput) (*models.Service, error) {
+	svc := models.Service{ID: id}
+	if err := applyService(&svc, in); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.UpdateService(ctx, &svc, eventSource(ctx)); err != nil {
+		return nil, mapServiceError(err)
+	}
+	return &svc, nil
+}
+
+// Delete удаляет сервис из каталога. Подписки на сервис сохраняют название
+func (s *CatalogService) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteService(ctx, id, eventSource(ctx)); err != nil {
+		return mapServiceError(err)
+	}
+	return nil
+}
+
+// applyService проверяет входные данные и записывает их в сервис.
+// Псевдонимы очищаются от пустых значений, повторов и совпадений с названием.
+func applyService(svc *models.Service, in ServiceInput) error {
+	verr := &ValidationError{}
+
+	name := strings.Join(strings.Fields(in.Name), " ")
+	if name == "" {
+		verr.Add("name", "is required")
+	}
+	if in.DefaultPrice != nil && *in.DefaultPrice < 0 {
+		verr.Add("default_price", "must not be negative")
+	}
+	if !verr.Empty() {
+		return verr
+	}
+
+	seen := map[string]bool{models.NormalizeServiceName(name): true}
+	aliases := []string{}
+	for _, alias := range in.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		normalized := models.NormalizeServiceName(alias)
+		if alias == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		aliases = append(aliases, alias)
+	}
+
+	svc.Name = name
+	svc.Aliases = aliases
+	svc.Category = strings.TrimSpace(in.Category)
+	svc.DefaultPrice = in.DefaultPrice
+	svc.VendorURL = strings.TrimSpace(in.VendorURL)
+	return nil
+}
+
+// mapServiceError переводит ошибки хранилища каталога в ошибки сервиса
+func mapServiceError(err error) error {
+	if errors.Is(err, repository.ErrServiceNotFound) {
+		return fmt.Errorf("service %w", ErrNotFound)
+	}
+	if errors.Is(err, repository.ErrServiceAliasExists) {
+		return fmt.Errorf("service name or alias is already used by another service: %w", ErrConflict)
+	}
+	return err
+}
+
+// resolveService связывает подписку с сервисом каталога по названию или псевдониму
+// и заменяет название каноническим. Подписка на сервис вне каталога остается без ссылки.
+func resolveService(ctx context.Context, repo repository.ServiceRepository, sub *models.Subscription) error {
+	svc, err := repo.ServiceByName(ctx, sub.ServiceName)
+	if errors.Is(err, repository.ErrServiceNotFound) {
+		sub.ServiceID = nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	sub.ServiceID = &svc.ID
+	sub.ServiceName = svc.Name
+	return nil
+}
+
+// resolveServiceFilter переводит фильтр по названию сервиса в фильтр по сервису каталога,
+// чтобы находились подписки, созданные под любым псевдонимом.
+// Название сервиса вне каталога возвращается без изменений.
+func resolveServiceFilter(ctx context.Context, repo repository.ServiceRepository, name string) (*uuid.UUID, string, error) {
+	if name == "" {
+		return nil, "", nil
+	}
+	svc, err := repo.ServiceByName(ctx, name)
+	if errors.Is(err, repository.ErrServiceNotFound) {
+		return nil, name, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return &svc.ID, "", nil
+}
@@ -0,0 +1,604 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"subscription-service/internal/currency"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// CostBreakdownItem описывает вклад одной подписки в суммарную стоимость.
+// Cost указан в валюте подписки.
+type CostBreakdownItem struct {
+	SubscriptionID string `json:"subscription_id" example:"2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a"`
+	ServiceName    string `json:"service_name" example:"Yandex Plus"`
+	UserID         string `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Price          int    `json:"price" example:"400"`
+	Currency       string `json:"currency" example:"RUB"`
+	BillingPeriod  string `json:"billing_period" example:"monthly"`
+	Months         int    `json:"months" example:"12"`
+	Cost           int64  `json:"cost" example:"4800"`
+}
+
+// CostSeriesBucket содержит расходы на подписки за один месяц
+type CostSeriesBucket struct {
+	Month               string `json:"month" example:"07-2025"`
+	TotalCost           int64  `json:"total_cost" example:"1200"`
+	ActiveSubscriptions int    `json:"active_subscriptions" example:"3"`
+}
+
+// CostGroup содержит суммарную стоимость подписок в одной группе.
+// Заполнены только поля, по которым выполнялась группировка.
+type CostGroup struct {
+	ServiceName   string `json:"service_name,omitempty" example:"Yandex Plus"`
+	UserID        string `json:"user_id,omitempty" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Month         string `json:"month,omitempty" example:"07-2025"`
+	TotalCost     int64  `json:"total_cost" example:"4800"`
+	Subscriptions int    `json:"subscriptions" example:"2"`
+}
+
+// TotalCostInput содержит параметры расчета суммарной стоимости. Даты в формате MM-YYYY.
+type TotalCostInput struct {
+	StartDate   string
+	EndDate     string
+	UserID      string
+	ServiceName string
+	GroupBy     string
+	Currency    string
+	Amortize    bool
+}
+
+// TotalCostReport содержит результат расчета суммарной стоимости.
+// Если задана группировка, заполняется Groups, иначе Breakdown.
+type TotalCostReport struct {
+	TotalCost int64
+	Currency  string
+	// Totals содержит суммы в исходных валютах подписок
+	Totals    map[string]int64
+	Breakdown []CostBreakdownItem
+	Groups    []CostGroup
+	// GroupBy - поля группировки в порядке из запроса
+	GroupBy []string
+}
+
+// CostSeriesInput содержит параметры помесячного ряда расходов. Даты в формате MM-YYYY.
+type CostSeriesInput struct {
+	StartDate   string
+	EndDate     string
+	UserID      string
+	ServiceName string
+	Currency    string
+	Amortize    bool
+}
+
+// CostSeriesReport содержит помесячный ряд расходов
+type CostSeriesReport struct {
+	Series   []CostSeriesBucket
+	Currency string
+}
+
+// monthStart приводит дату к первому числу месяца
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// maxSeriesMonths ограничивает длину помесячного ряда расходов
+const maxSeriesMonths = 240
+
+// maxCostMonths ограничивает длину периода расчета стоимости
+const maxCostMonths = 1200
+
+// monthsInPeriod возвращает все месяцы периода [from, to]
+func monthsInPeriod(from, to time.Time) []time.Time {
+	var months []time.Time
+	for m := monthStart(from); !m.After(to); m = m.AddDate(0, 1, 0) {
+		months = append(months, m)
+	}
+	return months
+}
+
+// activeRange возвращает первый и последний месяц периода [from, to], в которых действует подписка.
+// ok ложно, если подписка не действует в периоде. Приостановки не учитываются
+func activeRange(sub models.Subscription, from, to time.Time) (start, end time.Time, ok bool) {
+	start = monthStart(sub.StartDate)
+	if from.After(start) {
+		start = from
+	}
+	end = to
+	if sub.EndDate != nil {
+		if subEnd := monthStart(*sub.EndDate); subEnd.Before(end) {
+			end = subEnd
+		}
+	}
+	return start, end, !end.Before(start)
+}
+
+// activeMonthCount возвращает количество месяцев периода [from, to], в которых подписка активна,
+// не перебирая месяцы. Месяцы приостановки не учитываются
+func activeMonthCount(sub models.Subscription, from, to time.Time) int {
+	start, end, ok := activeRange(sub, from, to)
+	if !ok {
+		return 0
+	}
+	count := monthsSince(start, end) + 1
+	for _, pause := range sub.Pauses {
+		pausedFrom, pausedTo := pause.PausedFrom, end
+		if start.After(pausedFrom) {
+			pausedFrom = start
+		}
+		if pause.ResumedFrom != nil {
+			if last := pause.ResumedFrom.AddDate(0, -1, 0); last.Before(pausedTo) {
+				pausedTo = last
+			}
+		}
+		if !pausedTo.Before(pausedFrom) {
+			count -= monthsSince(pausedFrom, pausedTo) + 1
+		}
+	}
+	return count
+}
+
+// eachActiveMonth вызывает fn для каждого месяца периода [from, to], в котором подписка активна.
+// Период обрезается датами начала и окончания самой подписки, месяцы приостановки пропускаются.
+// Ошибка fn прекращает перебор и возвращается
+func eachActiveMonth(sub models.Subscription, from, to time.Time, fn func(month time.Time) error) error {
+	start, end, ok := activeRange(sub, from, to)
+	if !ok {
+		return nil
+	}
+	for m := start; !m.After(end); m = m.AddDate(0, 1, 0) {
+		if pausedIn(sub, m) {
+			continue
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// costOptions задает параметры расчета стоимости
+type costOptions struct {
+	rates    *currency.Rates
+	currency string
+	amortize bool
+}
+
+// monthCharge описывает списание по подписке за один месяц
+type monthCharge struct {
+	Month  time.Time
+	Amount int64
+}
+
+// monthsSince возвращает количество полных месяцев от start до month
+func monthsSince(start, month time.Time) int {
+	return (month.Year()-start.Year())*12 + int(month.Month()) - int(start.Month())
+}
+
+// billingRatio возвращает долю цены за период оплаты, приходящуюся на один месяц, в виде дроби
+func billingRatio(period string) (num, den int64) {
+	switch period {
+	case models.BillingPeriodWeekly:
+		// 52 недели в году / 12 месяцев
+		return 13, 3
+	case models.BillingPeriodQuarterly:
+		return 1, 3
+	case models.BillingPeriodAnnual:
+		return 1, 12
+	default:
+		return 1, 1
+	}
+}
+
+// weeklyRenewals возвращает количество еженедельных списаний, приходящихся на месяц
+func weeklyRenewals(start, month time.Time) int64 {
+	const week = 7 * 24 * time.Hour
+
+	first := month
+	if start.After(first) {
+		first = start
+	}
+	last := month.AddDate(0, 1, -1)
+
+	// Индексы списаний start + k недель, попадающих в [first, last]
+	from := (first.Sub(start) + week - 1) / week
+	to := last.Sub(start) / week
+	if to < from {
+		return 0
+	}
+	return int64(to - from + 1)
+}
+
+// priceAt возвращает цену подписки, действующую в месяце month.
+// До первого изменения в шкале действует первая цена шкалы, без шкалы - цена подписки.
+func priceAt(sub models.Subscription, month time.Time) int64 {
+	if len(sub.Prices) == 0 {
+		return int64(sub.Price)
+	}
+	price := sub.Prices[0].Price
+	for _, p := range sub.Prices {
+		if p.EffectiveFrom.After(month) {
+			break
+		}
+		price = p.Price
+	}
+	return int64(price)
+}
+
+// billingStart возвращает первый оплачиваемый месяц подписки - месяц после пробного периода
+func billingStart(sub models.Subscription) time.Time {
+	return monthStart(sub.StartDate).AddDate(0, sub.TrialMonths, 0)
+}
+
+// chargeDay возвращает день списания в месяце month. День billingDay, которого нет в месяце,
+// заменяется последним днем месяца; без billingDay списание приходится на первое число
+func chargeDay(month time.Time, billingDay *int) time.Time {
+	day := 1
+	if billingDay != nil {
+		day = *billingDay
+	}
+	if last := month.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return month.AddDate(0, 0, day-1)
+}
+
+// firstChargeDate возвращает дату первого списания по подписке - день списания
+// в первом оплачиваемом месяце. От неё отсчитываются еженедельные списания
+func firstChargeDate(sub models.Subscription) time.Time {
+	return chargeDay(billingStart(sub), sub.BillingDay)
+}
+
+// priceForMonth возвращает цену подписки в месяце month с учетом вводной цены,
+// которая действует первые IntroMonths месяцев после пробного периода
+func priceForMonth(sub models.Subscription, month time.Time) int64 {
+	if sub.IntroPrice != nil && monthsSince(billingStart(sub), month) < sub.IntroMonths {
+		return int64(*sub.IntroPrice)
+	}
+	return priceAt(sub, month)
+}
+
+// chargeForMonth рассчитывает списание по подписке в указанном месяце.
+// Без амортизации цена списывается целиком в месяцы продления периода оплаты,
+// с амортизацией - распределяется равномерно по месяцам без потери копеек.
+// Месяцы пробного периода бесплатны, периоды оплаты отсчитываются от его окончания.
+func chargeForMonth(sub models.Subscription, month time.Time, amortize bool) int64 {
+	start := billingStart(sub)
+	if month.Before(start) {
+		return 0
+	}
+	price := priceForMonth(sub, month)
+	index := int64(monthsSince(start, month))
+
+	if amortize {
+		num, den := billingRatio(sub.BillingPeriod)
+		return price*num*(index+1)/den - price*num*index/den
+	}
+
+	switch sub.BillingPeriod {
+	case models.BillingPeriodWeekly:
+		return price * weeklyRenewals(firstChargeDate(sub), month)
+	case models.BillingPeriodQuarterly:
+		if index%3 == 0 {
+			return price
+		}
+		return 0
+	case models.BillingPeriodAnnual:
+		if index%12 == 0 {
+			return price
+		}
+		return 0
+	default:
+		return price
+	}
+}
+
+// eachCharge вызывает fn для помесячных списаний по подписке за период [from, to].
+// Перебираются все месяцы, в которые подписка активна, в том числе без списаний
+func eachCharge(sub models.Subscription, from, to time.Time, amortize bool, fn func(charge monthCharge) error) error {
+	return eachActiveMonth(sub, from, to, func(month time.Time) error {
+		return fn(monthCharge{Month: month, Amount: chargeForMonth(sub, month, amortize)})
+	})
+}
+
+// subscriptionCost рассчитывает стоимость подписки за период [from, to] в валюте подписки
+// и в валюте расчета opts.currency. Каждое списание приводится к валюте расчета отдельно,
+// как в группах и помесячном ряде, чтобы итог совпадал с суммой групп
+func subscriptionCost(sub models.Subscription, from, to time.Time, opts costOptions) (CostBreakdownItem, int64, error) {
+	item := CostBreakdownItem{
+		SubscriptionID: sub.ID.String(),
+		ServiceName:    sub.ServiceName,
+		UserID:         sub.UserID.String(),
+		Price:          sub.Price,
+		Currency:       sub.Currency,
+		BillingPeriod:  sub.BillingPeriod,
+	}
+	var converted int64
+	err := eachCharge(sub, from, to, opts.amortize, func(charge monthCharge) error {
+		amount, err := opts.rates.Convert(charge.Amount, sub.Currency, opts.currency)
+		if err != nil {
+			return err
+		}
+		item.Months++
+		item.Cost += charge.Amount
+		converted += amount
+		return nil
+	})
+	return item, converted, err
+}
+
+// costSeries раскладывает стоимость подписок по месяцам периода
+func costSeries(subscriptions []models.Subscription, months []time.Time, opts costOptions) ([]CostSeriesBucket, error) {
+	series := make([]CostSeriesBucket, len(months))
+	index := make(map[time.Time]int, len(months))
+	for i, m := range months {
+		series[i] = CostSeriesBucket{Month: formatMonthYear(m)}
+		index[m] = i
+	}
+	if len(months) == 0 {
+		return series, nil
+	}
+
+	from, to := months[0], months[len(months)-1]
+	for _, sub := range subscriptions {
+		err := eachCharge(sub, from, to, opts.amortize, func(charge monthCharge) error {
+			amount, err := opts.rates.Convert(charge.Amount, sub.Currency, opts.currency)
+			if err != nil {
+				return err
+			}
+			bucket := &series[index[charge.Month]]
+			bucket.TotalCost += amount
+			bucket.ActiveSubscriptions++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return series, nil
+}
+
+// Поля, по которым можно группировать стоимость подписок
+const (
+	groupByServiceName = "service_name"
+	groupByUserID      = "user_id"
+	groupByMonth       = "month"
+)
+
+// parseGroupBy разбирает параметр group_by вида "service_name,month"
+func parseGroupBy(groupBy string) ([]string, error) {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(groupBy, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		switch field {
+		case groupByServiceName, groupByUserID, groupByMonth:
+		default:
+			return nil, fmt.Errorf("invalid group_by field %q, allowed: service_name, user_id, month", field)
+		}
+		if !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// groupCosts группирует стоимость подписок за период [from, to] по указанным полям.
+// Стоимость групп приводится к валюте расчета, группы упорядочены по убыванию стоимости.
+func groupCosts(subscriptions []models.Subscription, from, to time.Time, fields []string, opts costOptions) ([]CostGroup, error) {
+	groups := make(map[string]*CostGroup)
+	members := make(map[string]map[string]bool)
+	var keys []string
+
+	for _, sub := range subscriptions {
+		err := eachCharge(sub, from, to, opts.amortize, func(charge monthCharge) error {
+			amount, err := opts.rates.Convert(charge.Amount, sub.Currency, opts.currency)
+			if err != nil {
+				return err
+			}
+
+			var group CostGroup
+			for _, field := range fields {
+				switch field {
+				case groupByServiceName:
+					group.ServiceName = sub.ServiceName
+				case groupByUserID:
+					group.UserID = sub.UserID.String()
+				case groupByMonth:
+					group.Month = formatMonthYear(charge.Month)
+				}
+			}
+
+			key := group.ServiceName + "|" + group.UserID + "|" + group.Month
+			existing, ok := groups[key]
+			if !ok {
+				existing = &group
+				groups[key] = existing
+				members[key] = make(map[string]bool)
+				keys = append(keys, key)
+			}
+			existing.TotalCost += amount
+			members[key][sub.ID.String()] = true
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]CostGroup, 0, len(keys))
+	for _, key := range keys {
+		group := groups[key]
+		group.Subscriptions = len(members[key])
+		result = append(result, *group)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].TotalCost > result[j].TotalCost
+	})
+	return result, nil
+}
+
+// aggregateFilter строит фильтр расчета стоимости за период [from, to], ошибки добавляются в verr
+func aggregateFilter(userIDStr, serviceName string, from, to time.Time, verr *ValidationError) repository.AggregateFilter {
+	filter := repository.AggregateFilter{ServiceName: serviceName, From: from, To: to}
+	if userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			verr.Add("user_id", "invalid format, expected UUID")
+		}
+		filter.UserID = &userID
+	}
+	return filter
+}
+
+// costPeriod определяет период расчета стоимости.
+// Если указана только одна дата, период состоит из одного месяца.
+// Если даты не указаны, период начинается с начала каждой подписки и заканчивается текущим месяцем.
+func costPeriod(startStr, endStr string, verr *ValidationError) (from, to time.Time) {
+	var startDate, endDate time.Time
+	var err error
+
+	if startStr != "" {
+		if startDate, err = parseMonthYear(startStr); err != nil {
+			verr.Add("start_date", "invalid format, expected MM-YYYY")
+		}
+	}
+	if endStr != "" {
+		if endDate, err = parseMonthYear(endStr); err != nil {
+			verr.Add("end_date", "invalid format, expected MM-YYYY")
+		}
+	}
+
+	switch {
+	case startStr != "" && endStr != "":
+		if verr.Empty() && endDate.Before(startDate) {
+			verr.Add("end_date", "must not be before start_date")
+		}
+		return startDate, endDate
+	case startStr != "":
+		return startDate, startDate
+	case endStr != "":
+		return endDate, endDate
+	default:
+		return time.Time{}, monthStart(time.Now().UTC())
+	}
+}
+
+// TotalCost рассчитывает суммарную стоимость подписок за период
+func (s *SubscriptionService) TotalCost(ctx context.Context, in TotalCostInput) (*TotalCostReport, error) {
+	verr := &ValidationError{}
+
+	groupBy, err := parseGroupBy(in.GroupBy)
+	if err != nil {
+		verr.Add("group_by", err.Error())
+	}
+
+	targetCurrency, ok := s.resolveCurrency(in.Currency)
+	if !ok {
+		verr.Add("currency", "unsupported currency "+targetCurrency)
+	}
+
+	from, to := costPeriod(in.StartDate, in.EndDate, verr)
+	if verr.Empty() && !from.IsZero() && monthsSince(from, to)+1 > maxCostMonths {
+		verr.Add("end_date", fmt.Sprintf("period is too long, maximum is %d months", maxCostMonths))
+	}
+	filter := aggregateFilter(in.UserID, in.ServiceName, from, to, verr)
+	if !verr.Empty() {
+		return nil, verr
+	}
+
+	if filter.ServiceID, filter.ServiceName, err = resolveServiceFilter(ctx, s.repo, filter.ServiceName); err != nil {
+		return nil, err
+	}
+	subscriptions, err := s.repo.Aggregate(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TotalCostReport{
+		Currency:  targetCurrency,
+		Totals:    make(map[string]int64),
+		Breakdown: make([]CostBreakdownItem, 0, len(subscriptions)),
+	}
+	opts := costOptions{rates: s.rates, currency: targetCurrency, amortize: in.Amortize}
+	for _, sub := range subscriptions {
+		item, converted, err := subscriptionCost(sub, from, to, opts)
+		if err != nil {
+			return nil, err
+		}
+		if item.Months == 0 {
+			continue
+		}
+		report.TotalCost += converted
+		report.Totals[item.Currency] += item.Cost
+		report.Breakdown = append(report.Breakdown, item)
+	}
+
+	if len(groupBy) > 0 {
+		report.Groups, err = groupCosts(subscriptions, from, to, groupBy, opts)
+		if err != nil {
+			return nil, err
+		}
+		report.Breakdown = nil
+		report.GroupBy = groupBy
+	}
+
+	return report, nil
+}
+
+// CostSeries возвращает помесячный ряд расходов на подписки за период
+func (s *SubscriptionService) CostSeries(ctx context.Context, in CostSeriesInput) (*CostSeriesReport, error) {
+	verr := &ValidationError{}
+
+	if in.StartDate == "" {
+		verr.Add("start_date", "is required")
+	}
+	if in.EndDate == "" {
+		verr.Add("end_date", "is required")
+	}
+	from, to := costPeriod(in.StartDate, in.EndDate, verr)
+
+	targetCurrency, ok := s.resolveCurrency(in.Currency)
+	if !ok {
+		verr.Add("currency", "unsupported currency "+targetCurrency)
+	}
+
+	filter := aggregateFilter(in.UserID, in.ServiceName, from, to, verr)
+	if !verr.Empty() {
+		return nil, verr
+	}
+
+	if monthsSince(from, to)+1 > maxSeriesMonths {
+		return nil, NewValidationError("end_date", fmt.Sprintf("period is too long, maximum is %d months", maxSeriesMonths))
+	}
+	months := monthsInPeriod(from, to)
+
+	var err error
+	if filter.ServiceID, filter.ServiceName, err = resolveServiceFilter(ctx, s.repo, filter.ServiceName); err != nil {
+		return nil, err
+	}
+	subscriptions, err := s.repo.Aggregate(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := costOptions{rates: s.rates, currency: targetCurrency, amortize: in.Amortize}
+	series, err := costSeries(subscriptions, months, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CostSeriesReport{Series: series, Currency: targetCurrency}, nil
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrNotFound возвращается, если запрошенная сущность не найдена
+	ErrNotFound = errors.New("not found")
+	// ErrValidation возвращается при некорректных входных данных, подробности - в ValidationError
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict возвращается, если операция противоречит текущему состоянию данных
+	ErrConflict = errors.New("conflict")
+	// ErrPreconditionFailed возвращается, если версия сущности не совпадает с ожидаемой клиентом
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// ValidationError содержит ошибки валидации по полям запроса
+type ValidationError struct {
+	Fields map[string]string
+}
+
+// NewValidationError создает ошибку валидации одного поля
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: map[string]string{field: message}}
+}
+
+// Add добавляет ошибку поля
+func (e *ValidationError) Add(field, message string) {
+	if e.Fields == nil {
+		e.Fields = make(map[string]string)
+	}
+	e.Fields[field] = message
+}
+
+// Empty сообщает, что ошибок не добавлено
+func (e *ValidationError) Empty() bool {
+	return len(e.Fields) == 0
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s: %s", field, e.Fields[field]))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Is позволяет проверять ошибку через errors.Is(err, ErrValidation)
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+
+	"subscription-service/internal/models"
+)
+
+// ExportInput содержит фильтры и сортировку выгрузки подписок. Поля совпадают со списком подписок
+type ExportInput struct {
+	UserID      string
+	ServiceName string
+	Search      string
+	Currency    string
+	MinPrice    *int
+	MaxPrice    *int
+	ActiveAt    string
+	HasEndDate  *bool
+	Status      string
+	Sort        string
+}
+
+// Export вызывает fn для каждой подписки, подходящей под фильтры, не загружая их все в память.
+// Параметры проверяются до первого вызова fn.
+func (s *SubscriptionService) Export(ctx context.Context, in ExportInput, fn func(sub *models.Subscription) error) error {
+	filter, verr := listFilter(ListInput{
+		UserID:      in.UserID,
+		ServiceName: in.ServiceName,
+		Search:      in.Search,
+		Currency:    in.Currency,
+		MinPrice:    in.MinPrice,
+		MaxPrice:    in.MaxPrice,
+		ActiveAt:    in.ActiveAt,
+		HasEndDate:  in.HasEndDate,
+		Status:      in.Status,
+	})
+	sort, err := parseSort(in.Sort)
+	if err != nil {
+		verr.Add("sort", err.Error())
+	}
+	if !verr.Empty() {
+		return verr
+	}
+
+	if filter.ServiceID, filter.ServiceName, err = resolveServiceFilter(ctx, s.repo, filter.ServiceName); err != nil {
+		return err
+	}
+	return s.repo.Each(ctx, filter, sort, fn)
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+)
+
+// MaxIdempotencyKeyLength - максимальная длина ключа идемпотентности
+const MaxIdempotencyKeyLength = 255
+
+// DefaultIdempotencyTTL - срок хранения ключей идемпотентности по умолчанию
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// ErrIdempotencyMismatch возвращается, если ключ идемпотентности повторно использован с другими данными запроса
+var ErrIdempotencyMismatch = errors.New("idempotency key is already used with a different request")
+
+// requestHash возвращает SHA-256 данных запроса операции op
+func requestHash(op string, in interface{}) (string, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(op+":"), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreateIdempotent создает подписку с ключом идемпотентности key.
+// Повтор с тем же ключом и теми же данными в течение срока хранения ключа возвращает подписку
+// из ответа на первый запрос и replayed = true, повтор с другими данными - ErrIdempotencyMismatch.
+// Запросы, завершившиеся ошибкой, не сохраняются и могут быть повторены с тем же ключом.
+func (s *SubscriptionService) CreateIdempotent(ctx context.Context, key string, in CreateInput) (subscription *models.Subscription, replayed bool, err error) {
+	if len(key) > MaxIdempotencyKeyLength {
+		return nil, false, NewValidationError("Idempotency-Key", fmt.Sprintf("must be at most %d characters", MaxIdempotencyKeyLength))
+	}
+	hash, err := requestHash("create", in)
+	if err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now().UTC()
+	err = s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		stored, err := repo.IdempotencyKey(ctx, key, now)
+		if err == nil {
+			subscription, err = replay(stored, hash)
+			replayed = err == nil
+			return err
+		}
+		if !errors.Is(err, repository.ErrIdempotencyKeyNotFound) {
+			return err
+		}
+
+		subscription, err = s.create(ctx, repo, in)
+		if err != nil {
+			return err
+		}
+		response, err := json.Marshal(subscription)
+		if err != nil {
+			return err
+		}
+		return repo.SaveIdempotencyKey(ctx, &models.IdempotencyKey{
+			Key:         key,
+			RequestHash: hash,
+			Response:    response,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.opts.IdempotencyTTL),
+		})
+	})
+	if errors.Is(err, repository.ErrIdempotencyKeyExists) {
+		// Конкурентный запрос с тем же ключом завершился раньше: созданная подписка отменена,
+		// возвращается его результат
+		stored, err := s.repo.IdempotencyKey(ctx, key, now)
+		if err != nil {
+			return nil, false, err
+		}
+		subscription, err = replay(stored, hash)
+		return subscription, err == nil, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return subscription, replayed, nil
+}
+
+// replay возвращает подписку из сохраненного ответа, если запрос совпадает с сохраненным
+func replay(stored *models.IdempotencyKey, hash string) (*models.Subscription, error) {
+	if stored.RequestHash != hash {
+		return nil, ErrIdempotencyMismatch
+	}
+	var subscription models.Subscription
+	if err := json.Unmarshal(stored.Response, &subscription); err != nil {
+		return nil, fmt.Errorf("decode stored response: %w", err)
+	}
+	return &subscription, nil
+}
+
+// PurgeIdempotencyKeys удаляет ключи идемпотентности с истекшим сроком хранения и возвращает их количество
+func (s *SubscriptionService) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	return s.repo.PurgeIdempotencyKeys(ctx, time.Now().UTC())
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+)
+
+// ImportRow - строка файла импорта подписок
+type ImportRow struct {
+	// Line - номер строки в файле для отчета об ошибках
+	Line  int
+	Input CreateInput
+	// Err - ошибка разбора строки: такая строка не импортируется
+	Err error
+}
+
+// ImportRowError - ошибка импорта строки
+type ImportRowError struct {
+	Line int
+	Err  error
+}
+
+// ImportResult - результат импорта пачки строк
+type ImportResult struct {
+	Imported int
+	Errors   []ImportRowError
+}
+
+// Import создает подписки из строк в одной транзакции, пропуская ошибочные строки.
+// С dryRun строки проверяются так же, включая ограничения БД и конфликты между строками rows,
+// но ничего не сохраняется.
+func (s *SubscriptionService) Import(ctx context.Context, rows []ImportRow, dryRun bool) (*ImportResult, error) {
+	ops := make([]BatchOperation, len(rows))
+	for i, row := range rows {
+		ops[i] = BatchOperation{Op: BatchCreate, Create: row.Input, Err: row.Err}
+	}
+
+	batch, err := s.runBatch(ctx, ops, false, dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{}
+	for i, item := range batch.Items {
+		if item.Err != nil {
+			result.Errors = append(result.Errors, ImportRowError{Line: rows[i].Line, Err: item.Err})
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
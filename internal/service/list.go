@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Режимы пагинации списка подписок
+const (
+	PaginationOffset = "offset"
+	PaginationCursor = "cursor"
+)
+
+// defaultSort задает порядок списка подписок, если sort не указан
+const defaultSort = "created_at:asc"
+
+// Ограничения размера страницы списка
+const (
+	defaultListLimit = 10
+	maxListLimit     = 1000
+)
+
+// ListInput содержит параметры списка подписок. Пустые фильтры не применяются.
+type ListInput struct {
+	Pagination string
+	Page       int
+	Limit      int
+	Cursor     string
+	// WithTotal по умолчанию включен для offset-пагинации и выключен для cursor-пагинации
+	WithTotal *bool
+
+	UserID      string
+	ServiceName string
+	Search      string
+	Currency    string
+	MinPrice    *int
+	MaxPrice    *int
+	ActiveAt    string
+	HasEndDate  *bool
+	Status      string
+	Sort        string
+	// Deleted - список удаленных подписок (корзина) вместо действующих
+	Deleted bool
+}
+
+// ListOutput содержит страницу списка подписок
+type ListOutput struct {
+	Items     []models.Subscription
+	UseCursor bool
+	Page      int
+	Limit     int
+	// Total заполняется, если запрошено общее количество записей
+	Total *int64
+	// NextCursor заполняется в режиме cursor, если есть следующая страница
+	NextCursor *string
+}
+
+// List возвращает страницу списка подписок
+func (s *SubscriptionService) List(ctx context.Context, in ListInput) (*ListOutput, error) {
+	if in.Pagination == "" {
+		in.Pagination = PaginationOffset
+	}
+	if in.Page == 0 {
+		in.Page = 1
+	}
+	if in.Limit == 0 {
+		in.Limit = defaultListLimit
+	}
+
+	filter, verr := listFilter(in)
+	page := listPage(in, verr)
+	if !verr.Empty() {
+		return nil, verr
+	}
+
+	var err error
+	if filter.ServiceID, filter.ServiceName, err = resolveServiceFilter(ctx, s.repo, filter.ServiceName); err != nil {
+		return nil, err
+	}
+	result, err := s.repo.List(ctx, filter, page)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range result.Items {
+		setNextCharge(&result.Items[i])
+	}
+	out := &ListOutput{
+		Items:     result.Items,
+		UseCursor: page.UseCursor,
+		Page:      in.Page,
+		Limit:     in.Limit,
+	}
+	if page.WithTotal {
+		out.Total = &result.Total
+	}
+	if result.NextCursor != nil {
+		next := encodeCursor(*result.NextCursor, page.Desc)
+		out.NextCursor = &next
+	}
+	return out, nil
+}
+
+// parseSort разбирает параметр sort вида "price:desc,start_date:asc"
+func parseSort(sort string) ([]repository.SortField, error) {
+	if sort == "" {
+		sort = defaultSort
+	}
+
+	var fields []repository.SortField
+	for _, part := range strings.Split(sort, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		field, direction, _ := strings.Cut(part, ":")
+		if !repository.IsSortable(field) {
+			return nil, fmt.Errorf("invalid sort field %q", field)
+		}
+
+		switch strings.ToLower(direction) {
+		case "", "asc":
+			fields = append(fields, repository.SortField{Field: field})
+		case "desc":
+			fields = append(fields, repository.SortField{Field: field, Desc: true})
+		default:
+			return nil, fmt.Errorf("invalid sort direction %q, expected asc or desc", direction)
+		}
+	}
+
+	return fields, nil
+}
+
+// listCursor - сериализованное представление repository.Cursor вместе с направлением обхода
+type listCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+	Desc      bool      `json:"d,omitempty"`
+}
+
+// encodeCursor кодирует позицию записи и направление обхода в непрозрачную строку
+func encodeCursor(cursor repository.Cursor, desc bool) string {
+	data, _ := json.Marshal(listCursor{CreatedAt: cursor.CreatedAt, ID: cursor.ID, Desc: desc})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor декодирует строку, полученную из encodeCursor, и возвращает направление обхода, для которого она выдана
+func decodeCursor(cursor string) (repository.Cursor, bool, error) {
+	var c listCursor
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return repository.Cursor{}, false, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return repository.Cursor{}, false, fmt.Errorf("invalid cursor")
+	}
+	return repository.Cursor{CreatedAt: c.CreatedAt, ID: c.ID}, c.Desc, nil
+}
+
+// cursorDescending возвращает направление обхода по (created_at, id) в режиме cursor-пагинации.
+// В этом режиме допускается только сортировка по created_at.
+func cursorDescending(sort string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(sort)) {
+	case "", "created_at", "created_at:asc":
+		return false, nil
+	case "created_at:desc":
+		return true, nil
+	default:
+		return false, fmt.Errorf("cursor pagination supports only created_at:asc or created_at:desc")
+	}
+}
+
+// listFilter строит фильтр списка подписок
+func listFilter(in ListInput) (repository.ListFilter, *ValidationError) {
+	verr := &ValidationError{}
+	filter := repository.ListFilter{
+		ServiceName: in.ServiceName,
+		Search:      in.Search,
+		Currency:    strings.ToUpper(in.Currency),
+		MinPrice:    in.MinPrice,
+		MaxPrice:    in.MaxPrice,
+		HasEndDate:  in.HasEndDate,
+		Status:      in.Status,
+		Deleted:     in.Deleted,
+	}
+
+	if in.UserID != "" {
+		userID, err := uuid.Parse(in.UserID)
+		if err != nil {
+			verr.Add("user_id", "invalid format, expected UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	if in.MinPrice != nil && in.MaxPrice != nil && *in.MinPrice > *in.MaxPrice {
+		verr.Add("max_price", "must not be less than min_price")
+	}
+
+	if in.ActiveAt != "" {
+		activeAt, err := parseMonthYear(in.ActiveAt)
+		if err != nil {
+			verr.Add("active_at", "invalid format, expected MM-YYYY")
+		}
+		filter.ActiveAt = &activeAt
+	}
+
+	return filter, verr
+}
+
+// listPage строит параметры страницы списка подписок, ошибки добавляются в verr
+func listPage(in ListInput, verr *ValidationError) repository.Page {
+	page := repository.Page{
+		Limit:     in.Limit,
+		WithTotal: in.Pagination == PaginationOffset,
+	}
+	if in.WithTotal != nil {
+		page.WithTotal = *in.WithTotal
+	}
+
+	if in.Limit < 1 || in.Limit > maxListLimit {
+		verr.Add("limit", fmt.Sprintf("must be between 1 and %d", maxListLimit))
+	}
+
+	switch in.Pagination {
+	case PaginationCursor:
+		desc, err := cursorDescending(in.Sort)
+		if err != nil {
+			verr.Add("sort", err.Error())
+		}
+		page.UseCursor = true
+		page.Desc = desc
+
+		if in.Cursor != "" {
+			cursor, cursorDesc, err := decodeCursor(in.Cursor)
+			if err != nil {
+				verr.Add("cursor", err.Error())
+			} else if cursorDesc != desc {
+				// Курсор обратного обхода указывает на другую часть списка
+				verr.Add("cursor", "was issued for another sort direction, use the sort of the first page")
+			}
+			page.After = &cursor
+		}
+	case PaginationOffset:
+		if in.Cursor != "" {
+			verr.Add("cursor", "requires pagination=cursor")
+		}
+		if in.Page < 1 {
+			verr.Add("page", "must be at least 1")
+		}
+		sort, err := parseSort(in.Sort)
+		if err != nil {
+			verr.Add("sort", err.Error())
+		}
+		page.Sort = sort
+		page.Offset = (in.Page - 1) * in.Limit
+	default:
+		verr.Add("pagination", "must be offset or cursor")
+	}
+
+	return page
+}
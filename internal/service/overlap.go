@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Политики пересечения периодов подписок одного пользователя на один сервис
+const (
+	// OverlapReject запрещает пересечения
+	OverlapReject = "reject"
+	// OverlapWarn разрешает пересечения, сообщая о них в Subscription.Overlaps
+	OverlapWarn = "warn"
+	// OverlapAllow разрешает пересечения без проверки
+	OverlapAllow = "allow"
+)
+
+// Overlap - пара пересекающихся подписок одного пользователя на один сервис
+type Overlap struct {
+	UserID      string `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	ServiceName string `json:"service_name" example:"Yandex Plus"`
+	// From и To - первый и последний месяц пересечения, To пуст, если обе подписки бессрочные
+	From          string                `json:"from" example:"07-2025"`
+	To            string                `json:"to,omitempty" example:"12-2025"`
+	Subscriptions []models.Subscription `json:"subscriptions"`
+}
+
+// periodChanged сообщает, изменились ли поля, от которых зависит пересечение подписок
+func periodChanged(before, after models.Subscription) bool {
+	return before.UserID != after.UserID ||
+		before.ServiceName != after.ServiceName ||
+		!before.StartDate.Equal(after.StartDate) ||
+		!sameEndDate(before.EndDate, after.EndDate)
+}
+
+// sameEndDate сравнивает даты окончания подписок, nil означает бессрочную подписку
+func sameEndDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// checkOverlap проверяет пересечение sub с другими подписками пользователя на тот же сервис по политике сервиса.
+// При политике reject пересечение - ошибка, при warn ID пересекающихся подписок записываются в sub.Overlaps
+func (s *SubscriptionService) checkOverlap(ctx context.Context, repo repository.SubscriptionRepository, sub *models.Subscription) error {
+	if s.opts.OverlapPolicy == OverlapAllow {
+		return nil
+	}
+
+	overlapping, err := repo.Overlapping(ctx, *sub)
+	if err != nil {
+		return err
+	}
+	if len(overlapping) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, len(overlapping))
+	for i, other := range overlapping {
+		ids[i] = other.ID
+	}
+	if s.opts.OverlapPolicy == OverlapReject {
+		names := make([]string, len(ids))
+		for i, id := range ids {
+			names[i] = id.String()
+		}
+		return fmt.Errorf("subscription overlaps with subscriptions %s of the same user and service: %w", strings.Join(names, ", "), ErrConflict)
+	}
+	sub.Overlaps = ids
+	return nil
+}
+
+// Overlaps возвращает пары пересекающихся подписок, чтобы их можно было исправить
+func (s *SubscriptionService) Overlaps(ctx context.Context, userIDStr, serviceName string) ([]Overlap, error) {
+	filter := repository.OverlapFilter{ServiceName: serviceName}
+	if userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, NewValidationError("user_id", "invalid format, expected UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	var err error
+	if filter.ServiceID, filter.ServiceName, err = resolveServiceFilter(ctx, s.repo, filter.ServiceName); err != nil {
+		return nil, err
+	}
+	pairs, err := s.repo.Overlaps(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	overlaps := make([]Overlap, 0, len(pairs))
+	for _, pair := range pairs {
+		overlap := Overlap{
+			UserID:        pair.A.UserID.String(),
+			ServiceName:   pair.A.ServiceName,
+			From:          formatMonthYear(pair.B.StartDate),
+			Subscriptions: []models.Subscription{pair.A, pair.B},
+		}
+		// Пары упорядочены по началу: пересечение начинается с начала второй подписки
+		// и заканчивается с окончанием той, что закончилась раньше
+		end := pair.A.EndDate
+		if end == nil || (pair.B.EndDate != nil && pair.B.EndDate.Before(*end)) {
+			end = pair.B.EndDate
+		}
+		if end != nil {
+			overlap.To = formatMonthYear(*end)
+		}
+		overlaps = append(overlaps, overlap)
+	}
+	return overlaps, nil
+}
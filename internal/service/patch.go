@@ -0,0 +1,88 @@
+package service
+
+// PatchField описывает поле JSON Merge Patch (RFC 7396).
+// Если Set не установлен, поле отсутствует в патче и не изменяется;
+// Null означает явный null, который очищает поле.
+type PatchField[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// PatchInput содержит изменения подписки в формате JSON Merge Patch. Даты в формате MM-YYYY.
+type PatchInput struct {
+	ServiceName   PatchField[string]
+	Price         PatchField[int]
+	Currency      PatchField[string]
+	BillingPeriod PatchField[string]
+	BillingDay    PatchField[int]
+	UserID        PatchField[string]
+	StartDate     PatchField[string]
+	EndDate       PatchField[string]
+	TrialMonths   PatchField[int]
+	IntroPrice    PatchField[int]
+	IntroMonths   PatchField[int]
+	// EffectiveFrom - месяц (MM-YYYY), с которого действует новая цена. Не является полем подписки
+	EffectiveFrom PatchField[string]
+}
+
+// applyTo применяет патч к данным подписки.
+// null в обязательных полях считается ошибкой, в необязательных - сбрасывает значение по умолчанию.
+func (p PatchInput) applyTo(in *CreateInput) error {
+	verr := &ValidationError{}
+
+	applyRequired(&in.ServiceName, p.ServiceName, "service_name", verr)
+	applyRequired(&in.Price, p.Price, "price", verr)
+	applyRequired(&in.UserID, p.UserID, "user_id", verr)
+	applyRequired(&in.StartDate, p.StartDate, "start_date", verr)
+	applyOptional(&in.Currency, p.Currency)
+	applyOptional(&in.BillingPeriod, p.BillingPeriod)
+	applyNullable(&in.BillingDay, p.BillingDay)
+	applyOptional(&in.EndDate, p.EndDate)
+	applyOptional(&in.TrialMonths, p.TrialMonths)
+	applyNullable(&in.IntroPrice, p.IntroPrice)
+	applyOptional(&in.IntroMonths, p.IntroMonths)
+
+	if !verr.Empty() {
+		return verr
+	}
+	return nil
+}
+
+// applyRequired применяет изменение обязательного поля
+func applyRequired[T any](dst *T, field PatchField[T], name string, verr *ValidationError) {
+	if !field.Set {
+		return
+	}
+	if field.Null {
+		verr.Add(name, "cannot be null")
+		return
+	}
+	*dst = field.Value
+}
+
+// applyOptional применяет изменение необязательного поля, null сбрасывает его в нулевое значение
+func applyOptional[T any](dst *T, field PatchField[T]) {
+	if !field.Set {
+		return
+	}
+	var zero T
+	if field.Null {
+		*dst = zero
+		return
+	}
+	*dst = field.Value
+}
+
+// applyNullable применяет изменение поля-указателя, null сбрасывает его в nil
+func applyNullable[T any](dst **T, field PatchField[T]) {
+	if !field.Set {
+		return
+	}
+	if field.Null {
+		*dst = nil
+		return
+	}
+	value := field.Value
+	*dst = &value
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Pause приостанавливает действующую подписку с текущего месяца.
+// ifMatch задает версии, в которых подписку можно изменить (nil - без проверки).
+func (s *SubscriptionService) Pause(ctx context.Context, id uuid.UUID, ifMatch VersionMatch) (*models.Subscription, error) {
+	return s.transition(ctx, id, ifMatch, models.EventPaused, func(repo repository.SubscriptionRepository, sub *models.Subscription, now time.Time) error {
+		if sub.Status != models.StatusActive {
+			return transitionError("pause", sub.Status)
+		}
+		sub.Status = models.StatusPaused
+		sub.PausedAt = &now
+		return repo.SavePause(ctx, &models.SubscriptionPause{SubscriptionID: sub.ID, PausedFrom: monthStart(now)})
+	})
+}
+
+// Resume возобновляет приостановленную подписку с текущего месяца.
+// ifMatch задает версии, в которых подписку можно изменить (nil - без проверки).
+func (s *SubscriptionService) Resume(ctx context.Context, id uuid.UUID, ifMatch VersionMatch) (*models.Subscription, error) {
+	return s.transition(ctx, id, ifMatch, models.EventResumed, func(repo repository.SubscriptionRepository, sub *models.Subscription, now time.Time) error {
+		if sub.Status != models.StatusPaused {
+			return transitionError("resume", sub.Status)
+		}
+		pauses, err := repo.Pauses(ctx, sub.ID)
+		if err != nil {
+			return err
+		}
+		month := monthStart(now)
+		for i := range pauses {
+			if pauses[i].ResumedFrom == nil {
+				pauses[i].ResumedFrom = &month
+				if err := repo.SavePause(ctx, &pauses[i]); err != nil {
+					return err
+				}
+			}
+		}
+		sub.Status = models.StatusActive
+		sub.ResumedAt = &now
+		return nil
+	})
+}
+
+// Cancel отменяет действующую, приостановленную или пробную подписку. Подписка действует до конца
+// оплаченного периода: дата окончания переносится на последний месяц текущего периода оплаты
+// (для пробной подписки - на последний месяц пробного периода), если она не наступает раньше. ifMatch задает версии, в которых подписку можно изменить (nil - без проверки).
+func (s *SubscriptionService) Cancel(ctx context.Context, id uuid.UUID, ifMatch VersionMatch) (*models.Subscription, error) {
+	return s.transition(ctx, id, ifMatch, models.EventCancelled, func(repo repository.SubscriptionRepository, sub *models.Subscription, now time.Time) error {
+		if sub.Status != models.StatusActive && sub.Status != models.StatusPaused && sub.Status != models.StatusTrial {
+			return transitionError("cancel", sub.Status)
+		}
+		end := billingPeriodEnd(*sub, monthStart(now))
+		if sub.EndDate == nil || end.Before(*sub.EndDate) {
+			sub.EndDate = &end
+		}
+		sub.Status = models.StatusCancelled
+		sub.CancelledAt = &now
+		return nil
+	})
+}
+
+// ExpireEnded переводит в статус expired подписки, дата окончания которых прошла,
+// и возвращает их количество. Переходы записываются в журнал от имени system
+func (s *SubscriptionService) ExpireEnded(ctx context.Context) (int64, error) {
+	return s.repo.Expire(ctx, monthStart(time.Now().UTC()), repository.EventSource{Actor: repository.SystemActor})
+}
+
+// EndTrials переводит в статус active пробные подписки, пробный период которых закончился,
+// и возвращает их количество. Переходы записываются в журнал от имени system
+func (s *SubscriptionService) EndTrials(ctx context.Context) (int64, error) {
+	return s.repo.EndTrials(ctx, monthStart(time.Now().UTC()), repository.EventSource{Actor: repository.SystemActor})
+}
+
+// transition загружает подписку, применяет к ней переход change и сохраняет вместе с записью action в журнале
+func (s *SubscriptionService) transition(ctx context.Context, id uuid.UUID, ifMatch VersionMatch, action string, change func(repo repository.SubscriptionRepository, sub *models.Subscription, now time.Time) error) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		before, err := repo.Get(ctx, id)
+		if err != nil {
+			return mapRepoError(err)
+		}
+		if err := ifMatch.check(before.Version); err != nil {
+			return err
+		}
+
+		updated := *before
+		now := time.Now().UTC()
+		if err := change(repo, &updated, now); err != nil {
+			return err
+		}
+		refreshStatus(&updated, monthStart(now))
+
+		if err := repo.Update(ctx, &updated); err != nil {
+			return ifMatch.mapError(err)
+		}
+		subscription = &updated
+		return record(ctx, repo, action, id, before, &updated)
+	})
+	if err != nil {
+		return nil, err
+	}
+	setNextCharge(subscription)
+	return subscription, nil
+}
+
+// transitionError сообщает о недопустимом переходе из текущего статуса подписки
+func transitionError(action, status string) error {
+	return fmt.Errorf("cannot %s subscription with status %s: %w", action, status, ErrConflict)
+}
+
+// refreshStatus согласует статус подписки с датой окончания и пробным периодом: подписка,
+// закончившаяся раньше месяца month, истекает. Истекшая подписка, дату окончания которой перенесли,
+// получает статус, предшествовавший истечению, по времени последних переходов.
+// Действующая подписка находится в статусе trial, пока не закончился пробный период
+func refreshStatus(sub *models.Subscription, month time.Time) {
+	if sub.EndDate != nil && sub.EndDate.Before(month) {
+		sub.Status = models.StatusExpired
+		return
+	}
+	if sub.Status == "" || sub.Status == models.StatusExpired {
+		switch {
+		case sub.CancelledAt != nil:
+			sub.Status = models.StatusCancelled
+		case sub.PausedAt != nil && (sub.ResumedAt == nil || sub.PausedAt.After(*sub.ResumedAt)):
+			sub.Status = models.StatusPaused
+		default:
+			sub.Status = models.StatusActive
+		}
+	}
+
+	inTrial := month.Before(billingStart(*sub))
+	switch {
+	case sub.Status == models.StatusActive && inTrial:
+		sub.Status = models.StatusTrial
+	case sub.Status == models.StatusTrial && !inTrial:
+		sub.Status = models.StatusActive
+	}
+}
+
+// billingMonths возвращает длину периода оплаты в месяцах. Еженедельная подписка
+// оплачивается каждый месяц
+func billingMonths(period string) int {
+	switch period {
+	case models.BillingPeriodQuarterly:
+		return 3
+	case models.BillingPeriodAnnual:
+		return 12
+	default:
+		return 1
+	}
+}
+
+// billingPeriodEnd возвращает последний месяц периода оплаты, на который приходится month.
+// До окончания пробного периода - его последний месяц, для подписки без пробного периода,
+// которая ещё не началась, - последний месяц первого периода
+func billingPeriodEnd(sub models.Subscription, month time.Time) time.Time {
+	start := billingStart(sub)
+	length := billingMonths(sub.BillingPeriod)
+	index := monthsSince(start, month)
+	if index < 0 && sub.TrialMonths > 0 {
+		return start.AddDate(0, -1, 0)
+	}
+	if index < 0 {
+		index = 0
+	}
+	return start.AddDate(0, index/length*length+length-1, 0)
+}
+
+// pausedIn сообщает, приостановлена ли подписка в месяце month
+func pausedIn(sub models.Subscription, month time.Time) bool {
+	for _, pause := range sub.Pauses {
+		if pause.Covers(month) {
+			return true
+		}
+	}
+	return false
+}
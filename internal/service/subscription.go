@@ -0,0 +1,522 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"subscription-service/internal/currency"
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// SubscriptionService содержит бизнес-логику работы с подписками
+type SubscriptionService struct {
+	repo  repository.SubscriptionRepository
+	rates *currency.Rates
+	opts  Options
+}
+
+// Options задает настройки сервиса подписок. Незаданные поля получают значения по умолчанию
+type Options struct {
+	// IdempotencyTTL - срок хранения ключей идемпотентности, по умолчанию DefaultIdempotencyTTL
+	IdempotencyTTL time.Duration
+	// OverlapPolicy - политика пересечения подписок, по умолчанию OverlapAllow
+	OverlapPolicy string
+}
+
+func NewSubscriptionService(repo repository.SubscriptionRepository, rates *currency.Rates, opts Options) *SubscriptionService {
+	if opts.IdempotencyTTL <= 0 {
+		opts.IdempotencyTTL = DefaultIdempotencyTTL
+	}
+	if opts.OverlapPolicy == "" {
+		opts.OverlapPolicy = OverlapAllow
+	}
+	return &SubscriptionService{repo: repo, rates: rates, opts: opts}
+}
+
+// CreateInput содержит данные для создания или полной замены подписки. Даты в формате MM-YYYY.
+// Пустые Currency и BillingPeriod заменяются значениями по умолчанию, пустой EndDate означает бессрочную подписку.
+// BillingDay - день месяца списания (по умолчанию 1). TrialMonths - длина бесплатного пробного периода, IntroPrice - вводная цена первых IntroMonths месяцев после него.
+type CreateInput struct {
+	ServiceName   string
+	Price         int
+	Currency      string
+	BillingPeriod string
+	BillingDay    *int
+	UserID        string
+	StartDate     string
+	EndDate       string
+	TrialMonths   int
+	IntroPrice    *int
+	IntroMonths   int
+}
+
+// Допустимые годы в датах формата MM-YYYY
+const (
+	minYear = 1900
+	maxYear = 9999
+)
+
+// parseMonthYear парсит строку формата "MM-YYYY" в time.Time
+func parseMonthYear(dateStr string) (time.Time, error) {
+	parts := strings.Split(dateStr, "-")
+	if len(parts) != 2 {
+		return time.Time{}, errors.New("invalid date format, expected MM-YYYY")
+	}
+
+	month, err := strconv.Atoi(parts[0])
+	if err != nil || month < 1 || month > 12 {
+		return time.Time{}, errors.New("invalid month")
+	}
+
+	year, err := strconv.Atoi(parts[1])
+	if err != nil || year < minYear || year > maxYear {
+		return time.Time{}, errors.New("invalid year")
+	}
+
+	return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// formatMonthYear форматирует дату в строку формата "MM-YYYY"
+func formatMonthYear(t time.Time) string {
+	return t.Format("01-2006")
+}
+
+// validBillingPeriod сообщает, поддерживается ли период оплаты
+func validBillingPeriod(period string) bool {
+	switch period {
+	case models.BillingPeriodWeekly, models.BillingPeriodMonthly, models.BillingPeriodQuarterly, models.BillingPeriodAnnual:
+		return true
+	}
+	return false
+}
+
+// resolveCurrency возвращает код валюты или базовую валюту, если код не указан.
+// Валюта без известного курса считается неподдерживаемой.
+func (s *SubscriptionService) resolveCurrency(code string) (string, bool) {
+	if code == "" {
+		return s.rates.Base(), true
+	}
+	code = currency.Normalize(code)
+	return code, s.rates.Supports(code)
+}
+
+// mapRepoError переводит ошибки хранилища в ошибки сервиса
+func mapRepoError(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("subscription %w", ErrNotFound)
+	}
+	if errors.Is(err, repository.ErrInvalidDates) {
+		return NewValidationError("end_date", "must not be before start_date")
+	}
+	if errors.Is(err, repository.ErrNotDeleted) {
+		return fmt.Errorf("subscription is not deleted: %w", ErrConflict)
+	}
+	if errors.Is(err, repository.ErrOverlap) {
+		return fmt.Errorf("subscription overlaps with another subscription of the same user and service: %w", ErrConflict)
+	}
+	if errors.Is(err, repository.ErrVersionConflict) {
+		return fmt.Errorf("subscription was modified concurrently: %w", ErrConflict)
+	}
+	return err
+}
+
+// apply проверяет входные данные и записывает их в подписку.
+// startChanged и endChanged указывают, какие даты изменяются: если дата окончания
+// оказалась раньше даты начала, ошибка указывает на изменяемое поле.
+func (s *SubscriptionService) apply(sub *models.Subscription, in CreateInput, startChanged, endChanged bool) error {
+	verr := &ValidationError{}
+
+	if strings.TrimSpace(in.ServiceName) == "" {
+		verr.Add("service_name", "is required")
+	}
+	if in.Price < 0 {
+		verr.Add("price", "must not be negative")
+	}
+	if in.BillingDay != nil && (*in.BillingDay < 1 || *in.BillingDay > 31) {
+		verr.Add("billing_day", "must be between 1 and 31")
+	}
+	if in.TrialMonths < 0 {
+		verr.Add("trial_months", "must not be negative")
+	}
+	if in.IntroPrice != nil && *in.IntroPrice < 0 {
+		verr.Add("intro_price", "must not be negative")
+	}
+	if in.IntroMonths < 0 {
+		verr.Add("intro_months", "must not be negative")
+	}
+	if in.IntroPrice != nil && in.IntroMonths == 0 {
+		verr.Add("intro_months", "is required with intro_price")
+	}
+	if in.IntroPrice == nil && in.IntroMonths > 0 {
+		verr.Add("intro_price", "is required with intro_months")
+	}
+
+	userID, err := uuid.Parse(in.UserID)
+	if err != nil {
+		verr.Add("user_id", "invalid format, expected UUID")
+	}
+
+	startDate, startErr := parseMonthYear(in.StartDate)
+	if startErr != nil {
+		verr.Add("start_date", "invalid format, expected MM-YYYY")
+	}
+
+	subscriptionCurrency, ok := s.resolveCurrency(in.Currency)
+	if !ok {
+		verr.Add("currency", "unsupported currency "+subscriptionCurrency)
+	}
+
+	billingPeriod := in.BillingPeriod
+	if billingPeriod == "" {
+		billingPeriod = models.BillingPeriodMonthly
+	}
+	if !validBillingPeriod(billingPeriod) {
+		verr.Add("billing_period", "must be one of weekly, monthly, quarterly, annual")
+	}
+
+	var endDate *time.Time
+	if in.EndDate != "" {
+		parsed, err := parseMonthYear(in.EndDate)
+		if err != nil {
+			verr.Add("end_date", "invalid format, expected MM-YYYY")
+		} else {
+			endDate = &parsed
+		}
+	}
+
+	if startErr == nil && endDate != nil && endDate.Before(startDate) {
+		if endChanged || !startChanged {
+			verr.Add("end_date", "must not be before start_date")
+		} else {
+			verr.Add("start_date", "must not be after end_date")
+		}
+	}
+
+	if !verr.Empty() {
+		return verr
+	}
+
+	sub.ServiceName = in.ServiceName
+	sub.Price = in.Price
+	sub.Currency = subscriptionCurrency
+	sub.BillingPeriod = billingPeriod
+	sub.BillingDay = in.BillingDay
+	sub.UserID = userID
+	sub.StartDate = startDate
+	sub.EndDate = endDate
+	sub.TrialMonths = in.TrialMonths
+	sub.IntroPrice = in.IntroPrice
+	sub.IntroMonths = in.IntroMonths
+	return nil
+}
+
+// inputFromSubscription возвращает текущие данные подписки в виде CreateInput
+func inputFromSubscription(sub models.Subscription) CreateInput {
+	in := CreateInput{
+		ServiceName:   sub.ServiceName,
+		Price:         sub.Price,
+		Currency:      sub.Currency,
+		BillingPeriod: sub.BillingPeriod,
+		BillingDay:    sub.BillingDay,
+		UserID:        sub.UserID.String(),
+		StartDate:     formatMonthYear(sub.StartDate),
+		TrialMonths:   sub.TrialMonths,
+		IntroPrice:    sub.IntroPrice,
+		IntroMonths:   sub.IntroMonths,
+	}
+	if sub.EndDate != nil {
+		in.EndDate = formatMonthYear(*sub.EndDate)
+	}
+	return in
+}
+
+// Create создает подписку
+func (s *SubscriptionService) Create(ctx context.Context, in CreateInput) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		var err error
+		subscription, err = s.create(ctx, repo, in)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// create создает подписку через repo вместе с начальной ценой и записью в журнале
+func (s *SubscriptionService) create(ctx context.Context, repo repository.SubscriptionRepository, in CreateInput) (*models.Subscription, error) {
+	var subscription models.Subscription
+	if err := s.apply(&subscription, in, true, true); err != nil {
+		return nil, err
+	}
+	if err := resolveService(ctx, repo, &subscription); err != nil {
+		return nil, err
+	}
+	if err := repo.EnsureUser(ctx, subscription.UserID); err != nil {
+		return nil, err
+	}
+	refreshStatus(&subscription, monthStart(time.Now().UTC()))
+	if err := s.checkOverlap(ctx, repo, &subscription); err != nil {
+		return nil, err
+	}
+
+	if err := repo.Create(ctx, &subscription); err != nil {
+		return nil, mapRepoError(err)
+	}
+	if err := repo.SetPrice(ctx, &models.SubscriptionPrice{
+		SubscriptionID: subscription.ID,
+		EffectiveFrom:  subscription.StartDate,
+		Price:          subscription.Price,
+	}); err != nil {
+		return nil, err
+	}
+	if err := record(ctx, repo, models.EventCreated, subscription.ID, nil, &subscription); err != nil {
+		return nil, err
+	}
+	setNextCharge(&subscription)
+	return &subscription, nil
+}
+
+// Get возвращает подписку по ID
+func (s *SubscriptionService) Get(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	subscription, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, mapRepoError(err)
+	}
+	setNextCharge(subscription)
+	return subscription, nil
+}
+
+// Replace полностью заменяет данные подписки.
+// effectiveFrom - месяц (MM-YYYY), с которого действует новая цена; пустое значение - текущий месяц.
+// ifMatch задает версии, в которых подписку можно изменить (nil - без проверки).
+func (s *SubscriptionService) Replace(ctx context.Context, id uuid.UUID, in CreateInput, effectiveFrom string, ifMatch VersionMatch) (*models.Subscription, error) {
+	priceFrom, err := parseEffectiveFrom(effectiveFrom)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription *models.Subscription
+	err = s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		var err error
+		subscription, err = s.update(ctx, repo, id, ifMatch, priceFrom, func(subscription *models.Subscription) error {
+			return s.apply(subscription, in, true, true)
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// Patch применяет к подписке изменения в формате JSON Merge Patch (RFC 7396).
+// ifMatch задает версии, в которых подписку можно изменить (nil - без проверки).
+func (s *SubscriptionService) Patch(ctx context.Context, id uuid.UUID, patch PatchInput, ifMatch VersionMatch) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		var err error
+		subscription, err = s.patch(ctx, repo, id, patch, ifMatch)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// patch применяет JSON Merge Patch к подписке через repo
+func (s *SubscriptionService) patch(ctx context.Context, repo repository.SubscriptionRepository, id uuid.UUID, patch PatchInput, ifMatch VersionMatch) (*models.Subscription, error) {
+	var priceFrom *time.Time
+	if patch.EffectiveFrom.Set && !patch.EffectiveFrom.Null {
+		if !patch.Price.Set || patch.Price.Null {
+			return nil, NewValidationError("effective_from", "requires price")
+		}
+		parsed, err := parseEffectiveFrom(patch.EffectiveFrom.Value)
+		if err != nil {
+			return nil, err
+		}
+		priceFrom = parsed
+	}
+	return s.update(ctx, repo, id, ifMatch, priceFrom, func(subscription *models.Subscription) error {
+		in := inputFromSubscription(*subscription)
+		if err := patch.applyTo(&in); err != nil {
+			return err
+		}
+		return s.apply(subscription, in, patch.StartDate.Set, patch.EndDate.Set)
+	})
+}
+
+// update загружает подписку через repo, применяет к ней change и сохраняет вместе с записью в журнале.
+// Если цена изменилась или указан priceFrom, новая цена добавляется в шкалу цен
+// с месяца priceFrom (по умолчанию - с текущего месяца).
+func (s *SubscriptionService) update(ctx context.Context, repo repository.SubscriptionRepository, id uuid.UUID, ifMatch VersionMatch, priceFrom *time.Time, change func(subscription *models.Subscription) error) (*models.Subscription, error) {
+	before, err := repo.Get(ctx, id)
+	if err != nil {
+		return nil, mapRepoError(err)
+	}
+	if err := ifMatch.check(before.Version); err != nil {
+		return nil, err
+	}
+
+	updated := *before
+	if err := change(&updated); err != nil {
+		return nil, err
+	}
+	if err := resolveService(ctx, repo, &updated); err != nil {
+		return nil, err
+	}
+	if updated.UserID != before.UserID {
+		if err := repo.EnsureUser(ctx, updated.UserID); err != nil {
+			return nil, err
+		}
+	}
+	refreshStatus(&updated, monthStart(time.Now().UTC()))
+	if periodChanged(*before, updated) {
+		if err := s.checkOverlap(ctx, repo, &updated); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := repo.Update(ctx, &updated); err != nil {
+		return nil, ifMatch.mapError(err)
+	}
+	if updated.Price != before.Price || priceFrom != nil {
+		if err := repo.SetPrice(ctx, &models.SubscriptionPrice{
+			SubscriptionID: id,
+			EffectiveFrom:  priceEffectiveFrom(updated, priceFrom),
+			Price:          updated.Price,
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := record(ctx, repo, models.EventUpdated, id, before, &updated); err != nil {
+		return nil, err
+	}
+	setNextCharge(&updated)
+	return &updated, nil
+}
+
+// parseEffectiveFrom парсит месяц, с которого действует новая цена. Пустая строка дает nil.
+func parseEffectiveFrom(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	month, err := parseMonthYear(value)
+	if err != nil {
+		return nil, NewValidationError("effective_from", "invalid format, expected MM-YYYY")
+	}
+	return &month, nil
+}
+
+// priceEffectiveFrom возвращает месяц, с которого действует новая цена подписки:
+// from или текущий месяц, но не раньше начала подписки
+func priceEffectiveFrom(sub models.Subscription, from *time.Time) time.Time {
+	month := monthStart(time.Now().UTC())
+	if from != nil {
+		month = *from
+	}
+	if start := monthStart(sub.StartDate); month.Before(start) {
+		return start
+	}
+	return month
+}
+
+// Prices возвращает шкалу цен подписки
+func (s *SubscriptionService) Prices(ctx context.Context, id uuid.UUID) ([]models.SubscriptionPrice, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, mapRepoError(err)
+	}
+	prices, err := s.repo.Prices(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if prices == nil {
+		prices = []models.SubscriptionPrice{}
+	}
+	return prices, nil
+}
+
+// Delete удаляет подписку.
+// ifMatch задает версии, в которых подписку можно удалить (nil - без проверки).
+func (s *SubscriptionService) Delete(ctx context.Context, id uuid.UUID, ifMatch VersionMatch) error {
+	return s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		return s.delete(ctx, repo, id, ifMatch)
+	})
+}
+
+// delete перемещает подписку в корзину через repo и записывает это в журнал
+func (s *SubscriptionService) delete(ctx context.Context, repo repository.SubscriptionRepository, id uuid.UUID, ifMatch VersionMatch) error {
+	subscription, err := repo.Get(ctx, id)
+	if err != nil {
+		return mapRepoError(err)
+	}
+	if err := ifMatch.check(subscription.Version); err != nil {
+		return err
+	}
+
+	if err := repo.Delete(ctx, id, subscription.Version); err != nil {
+		return ifMatch.mapError(err)
+	}
+	return record(ctx, repo, models.EventDeleted, id, subscription, nil)
+}
+
+// HardDelete безвозвратно удаляет подписку, в том числе находящуюся в корзине.
+// ifMatch задает версии, в которых подписку можно удалить (nil - без проверки).
+func (s *SubscriptionService) HardDelete(ctx context.Context, id uuid.UUID, ifMatch VersionMatch) error {
+	return s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		subscription, err := repo.GetWithDeleted(ctx, id)
+		if err != nil {
+			return mapRepoError(err)
+		}
+		if err := ifMatch.check(subscription.Version); err != nil {
+			return err
+		}
+
+		if err := repo.HardDelete(ctx, id, []int64{subscription.Version}); err != nil {
+			return ifMatch.mapError(err)
+		}
+
+		// Подписка из корзины уже записана в журнал как удаленная
+		var before *models.Subscription
+		if !subscription.DeletedAt.Valid {
+			before = subscription
+		}
+		return record(ctx, repo, models.EventHardDeleted, id, before, nil)
+	})
+}
+
+// Restore восстанавливает удаленную подписку из корзины
+func (s *SubscriptionService) Restore(ctx context.Context, id uuid.UUID) (*models.Subscription, error) {
+	var subscription *models.Subscription
+	err := s.repo.InTx(ctx, func(repo repository.SubscriptionRepository) error {
+		restored, err := repo.Restore(ctx, id)
+		if err != nil {
+			return mapRepoError(err)
+		}
+		if err := s.checkOverlap(ctx, repo, restored); err != nil {
+			return err
+		}
+		subscription = restored
+		return record(ctx, repo, models.EventRestored, id, nil, restored)
+	})
+	if err != nil {
+		return nil, err
+	}
+	setNextCharge(subscription)
+	return subscription, nil
+}
+
+// PurgeDeleted безвозвратно удаляет подписки, находящиеся в корзине дольше retention,
+// и возвращает их количество
+func (s *SubscriptionService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Purge(ctx, time.Now().UTC().Add(-retention))
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// summaryUpcomingEnds ограничивает количество ближайших дат окончания в сводке пользователя
+const summaryUpcomingEnds = 5
+
+// UserSummaryInput содержит параметры сводки пользователя
+type UserSummaryInput struct {
+	Currency string
+	Amortize bool
+}
+
+// UpcomingEnd - ближайшая дата окончания действующей подписки
+type UpcomingEnd struct {
+	SubscriptionID string    `json:"subscription_id" example:"2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a"`
+	ServiceName    string    `json:"service_name" example:"Yandex Plus"`
+	EndDate        time.Time `json:"end_date" example:"2025-12-01T00:00:00Z"`
+}
+
+// ServiceSpend - месячные расходы пользователя на один сервис
+type ServiceSpend struct {
+	ServiceName string `json:"service_name" example:"Yandex Plus"`
+	MonthlyCost int64  `json:"monthly_cost" example:"400"`
+}
+
+// UserSummary - сводка по подпискам пользователя на текущий месяц. Суммы указаны в валюте Currency
+type UserSummary struct {
+	UserID   string `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	Month    string `json:"month" example:"07-2025"`
+	Currency string `json:"currency" example:"RUB"`
+	// ActiveSubscriptions - подписки, действующие в текущем месяце
+	ActiveSubscriptions []models.Subscription `json:"active_subscriptions"`
+	// MonthlySpend - расходы за текущий месяц
+	MonthlySpend int64 `json:"monthly_spend" example:"1200"`
+	// YearToDateSpend - расходы с начала года по текущий месяц включительно
+	YearToDateSpend int64 `json:"year_to_date_spend" example:"8400"`
+	// UpcomingEndDates - ближайшие даты окончания действующих подписок
+	UpcomingEndDates []UpcomingEnd `json:"upcoming_end_dates"`
+	// MostExpensiveService - сервис с наибольшей стоимостью в месяц с учетом периода оплаты
+	MostExpensiveService *ServiceSpend `json:"most_expensive_service"`
+}
+
+// UserSummary собирает сводку по подпискам пользователя на текущий месяц
+func (s *SubscriptionService) UserSummary(ctx context.Context, userID uuid.UUID, in UserSummaryInput) (*UserSummary, error) {
+	targetCurrency, ok := s.resolveCurrency(in.Currency)
+	if !ok {
+		return nil, NewValidationError("currency", "unsupported currency "+targetCurrency)
+	}
+	if _, err := s.repo.GetUser(ctx, userID); err != nil {
+		return nil, mapUserError(err)
+	}
+
+	month := monthStart(time.Now().UTC())
+	yearStart := time.Date(month.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	subscriptions, err := s.repo.Aggregate(ctx, repository.AggregateFilter{UserID: &userID, From: yearStart, To: month})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &UserSummary{
+		UserID:              userID.String(),
+		Month:               formatMonthYear(month),
+		Currency:            targetCurrency,
+		ActiveSubscriptions: []models.Subscription{},
+		UpcomingEndDates:    []UpcomingEnd{},
+	}
+	serviceCosts := make(map[string]int64)
+	for _, sub := range subscriptions {
+		_, ytd, err := subscriptionCost(sub, yearStart, month, costOptions{rates: s.rates, currency: targetCurrency, amortize: in.Amortize})
+		if err != nil {
+			return nil, err
+		}
+		summary.YearToDateSpend += ytd
+
+		if activeMonthCount(sub, month, month) == 0 {
+			continue
+		}
+		monthly, err := s.rates.Convert(chargeForMonth(sub, month, in.Amortize), sub.Currency, targetCurrency)
+		if err != nil {
+			return nil, err
+		}
+		amortized, err := s.rates.Convert(chargeForMonth(sub, month, true), sub.Currency, targetCurrency)
+		if err != nil {
+			return nil, err
+		}
+		summary.MonthlySpend += monthly
+		serviceCosts[sub.ServiceName] += amortized
+
+		sub.Prices = nil
+		summary.ActiveSubscriptions = append(summary.ActiveSubscriptions, sub)
+		if sub.EndDate != nil {
+			summary.UpcomingEndDates = append(summary.UpcomingEndDates, UpcomingEnd{
+				SubscriptionID: sub.ID.String(),
+				ServiceName:    sub.ServiceName,
+				EndDate:        *sub.EndDate,
+			})
+		}
+	}
+
+	sort.SliceStable(summary.UpcomingEndDates, func(i, j int) bool {
+		return summary.UpcomingEndDates[i].EndDate.Before(summary.UpcomingEndDates[j].EndDate)
+	})
+	if len(summary.UpcomingEndDates) > summaryUpcomingEnds {
+		summary.UpcomingEndDates = summary.UpcomingEndDates[:summaryUpcomingEnds]
+	}
+
+	for name, cost := range serviceCosts {
+		top := summary.MostExpensiveService
+		if top == nil || cost > top.MonthlyCost || (cost == top.MonthlyCost && name < top.ServiceName) {
+			summary.MostExpensiveService = &ServiceSpend{ServiceName: name, MonthlyCost: cost}
+		}
+	}
+	return summary, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// EndingTrial - пробная подписка, пробный период которой скоро закончится
+type EndingTrial struct {
+	Subscription models.Subscription `json:"subscription"`
+	// TrialEndsAt - день окончания пробного периода, с которого начинается оплата
+	TrialEndsAt time.Time `json:"trial_ends_at" example:"2025-08-01T00:00:00Z"`
+	// FirstChargeDate - день первого списания с учетом billing_day подписки
+	FirstChargeDate time.Time `json:"first_charge_date" example:"2025-08-15T00:00:00Z"`
+	// FirstCharge - первое списание после пробного периода в валюте подписки, с учетом вводной цены
+	FirstCharge int64  `json:"first_charge" example:"199"`
+	Currency    string `json:"currency" example:"RUB"`
+}
+
+// EndingTrials возвращает пробные подписки, пробный период которых заканчивается в ближайшие days дней,
+// в порядке окончания
+func (s *SubscriptionService) EndingTrials(ctx context.Context, userIDStr string, days int) ([]EndingTrial, error) {
+	if days < 1 {
+		return nil, NewValidationError("days", "must be positive")
+	}
+	now := time.Now().UTC()
+	until := now.AddDate(0, 0, days)
+
+	filter := repository.AggregateFilter{From: monthStart(now), To: monthStart(until)}
+	if userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, NewValidationError("user_id", "invalid format, expected UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	subscriptions, err := s.repo.Aggregate(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	trials := []EndingTrial{}
+	for _, sub := range subscriptions {
+		end := billingStart(sub)
+		if sub.Status != models.StatusTrial || !end.After(now) || end.After(until) {
+			continue
+		}
+		// Подписка, закончившаяся или приостановленная до первого оплачиваемого месяца, не списывается
+		if activeMonthCount(sub, end, end) == 0 {
+			continue
+		}
+		trials = append(trials, EndingTrial{
+			Subscription:    sub,
+			TrialEndsAt:     end,
+			FirstChargeDate: firstChargeDate(sub),
+			FirstCharge:     priceForMonth(sub, end),
+			Currency:        sub.Currency,
+		})
+	}
+
+	sort.SliceStable(trials, func(i, j int) bool {
+		if !trials[i].TrialEndsAt.Equal(trials[j].TrialEndsAt) {
+			return trials[i].TrialEndsAt.Before(trials[j].TrialEndsAt)
+		}
+		return trials[i].Subscription.ServiceName < trials[j].Subscription.ServiceName
+	})
+	return trials, nil
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// UpcomingCharge - предстоящее списание по подписке
+type UpcomingCharge struct {
+	Date           time.Time `json:"date" example:"2025-08-15T00:00:00Z"`
+	SubscriptionID string    `json:"subscription_id" example:"2b1c7d3e-6a8f-4a3b-9c1d-1f2e3d4c5b6a"`
+	ServiceName    string    `json:"service_name" example:"Yandex Plus"`
+	UserID         string    `json:"user_id" example:"60601fee-2bf1-4721-ae6f-7636e79a0cba"`
+	// Amount - сумма списания в валюте подписки
+	Amount   int64  `json:"amount" example:"400"`
+	Currency string `json:"currency" example:"RUB"`
+}
+
+// charge - списание по подписке в конкретный день
+type charge struct {
+	Date   time.Time
+	Amount int64
+}
+
+// UpcomingCharges возвращает списания по подпискам в ближайшие days дней, начиная с сегодняшнего,
+// в порядке дат
+func (s *SubscriptionService) UpcomingCharges(ctx context.Context, userIDStr string, days int) ([]UpcomingCharge, error) {
+	if days < 1 {
+		return nil, NewValidationError("days", "must be positive")
+	}
+	from := today()
+	to := from.AddDate(0, 0, days)
+
+	filter := repository.AggregateFilter{From: monthStart(from), To: monthStart(to)}
+	if userIDStr != "" {
+		userID, err := uuid.Parse(userIDStr)
+		if err != nil {
+			return nil, NewValidationError("user_id", "invalid format, expected UUID")
+		}
+		filter.UserID = &userID
+	}
+
+	subscriptions, err := s.repo.Aggregate(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := []UpcomingCharge{}
+	for _, sub := range subscriptions {
+		for _, c := range chargesBetween(sub, from, to) {
+			upcoming = append(upcoming, UpcomingCharge{
+				Date:           c.Date,
+				SubscriptionID: sub.ID.String(),
+				ServiceName:    sub.ServiceName,
+				UserID:         sub.UserID.String(),
+				Amount:         c.Amount,
+				Currency:       sub.Currency,
+			})
+		}
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		a, b := upcoming[i], upcoming[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.ServiceName != b.ServiceName {
+			return a.ServiceName < b.ServiceName
+		}
+		return a.SubscriptionID < b.SubscriptionID
+	})
+	return upcoming, nil
+}
+
+// chargesBetween возвращает списания по подписке с from по to включительно.
+// Месяцы пробного периода и приостановки не оплачиваются, длинные периоды оплаты
+// списываются в день списания месяца продления, еженедельные - каждые 7 дней от первого списания
+func chargesBetween(sub models.Subscription, from, to time.Time) []charge {
+	var charges []charge
+	add := func(date time.Time, amount int64) {
+		if !date.Before(from) && !date.After(to) {
+			charges = append(charges, charge{Date: date, Amount: amount})
+		}
+	}
+
+	start := billingStart(sub)
+	length := billingMonths(sub.BillingPeriod)
+	_ = eachActiveMonth(sub, monthStart(from), monthStart(to), func(month time.Time) error {
+		if month.Before(start) {
+			return nil
+		}
+		if sub.BillingPeriod != models.BillingPeriodWeekly {
+			if monthsSince(start, month)%length == 0 {
+				add(chargeDay(month, sub.BillingDay), chargeForMonth(sub, month, false))
+			}
+			return nil
+		}
+
+		price := priceForMonth(sub, month)
+		next := month.AddDate(0, 1, 0)
+		date := firstChargeDate(sub)
+		if date.Before(month) {
+			weeks := (month.Sub(date) + 7*24*time.Hour - 1) / (7 * 24 * time.Hour)
+			date = date.AddDate(0, 0, 7*int(weeks))
+		}
+		for ; date.Before(next); date = date.AddDate(0, 0, 7) {
+			add(date, price)
+		}
+		return nil
+	})
+	return charges
+}
+
+// nextChargeDate возвращает дату ближайшего списания по подписке начиная с дня day
+// или nil, если списаний больше не будет или подписка приостановлена
+func nextChargeDate(sub models.Subscription, day time.Time) *time.Time {
+	if sub.Status == models.StatusPaused {
+		return nil
+	}
+	from := day
+	if first := firstChargeDate(sub); first.After(from) {
+		from = first
+	}
+	// Ближайшее списание приходится не позже чем через самый длинный период оплаты - год
+	charges := chargesBetween(sub, from, from.AddDate(1, 0, 0))
+	if len(charges) == 0 {
+		return nil
+	}
+	return &charges[0].Date
+}
+
+// setNextCharge заполняет дату следующего списания подписки
+func setNextCharge(sub *models.Subscription) {
+	if sub != nil {
+		sub.NextChargeDate = nextChargeDate(*sub, today())
+	}
+}
+
+// today возвращает начало текущего дня в UTC
+func today() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"subscription-service/internal/models"
+	"subscription-service/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// UserService содержит бизнес-логику работы с пользователями
+type UserService struct {
+	repo repository.UserRepository
+}
+
+func NewUserService(repo repository.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// UserInput содержит данные пользователя. Пустой ID при создании генерируется автоматически
+type UserInput struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+// Create добавляет пользователя
+func (s *UserService) Create(ctx context.Context, in UserInput) (*models.User, error) {
+	user := models.User{
+		Name:  strings.TrimSpace(in.Name),
+		Email: strings.TrimSpace(in.Email),
+	}
+	if in.ID != "" {
+		id, err := uuid.Parse(in.ID)
+		if err != nil {
+			return nil, NewValidationError("id", "invalid format, expected UUID")
+		}
+		user.ID = id
+	}
+
+	if err := s.repo.CreateUser(ctx, &user); err != nil {
+		return nil, mapUserError(err)
+	}
+	return &user, nil
+}
+
+// Get возвращает пользователя по ID
+func (s *UserService) Get(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, mapUserError(err)
+	}
+	return user, nil
+}
+
+// Update заменяет имя и email пользователя
+func (s *UserService) Update(ctx context.Context, id uuid.UUID, in UserInput) (*models.User, error) {
+	user := models.User{
+		ID:    id,
+		Name:  strings.TrimSpace(in.Name),
+		Email: strings.TrimSpace(in.Email),
+	}
+	if err := s.repo.UpdateUser(ctx, &user); err != nil {
+		return nil, mapUserError(err)
+	}
+	return &user, nil
+}
+
+// mapUserError переводит ошибки хранилища пользователей в ошибки сервиса
+func mapUserError(err error) error {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+	if errors.Is(err, repository.ErrUserExists) {
+		return fmt.Errorf("user already exists: %w", ErrConflict)
+	}
+	return err
+}
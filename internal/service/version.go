@@ -0,0 +1,35 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"subscription-service/internal/repository"
+)
+
+// VersionMatch - условие If-Match на версию подписки.
+// nil означает отсутствие условия, иначе текущая версия должна быть одной из перечисленных.
+// Пустой, но не nil список (например, из нераспознанных ETag) не совпадает ни с одной версией.
+type VersionMatch []int64
+
+// check проверяет, что версия подписки удовлетворяет условию
+func (m VersionMatch) check(version int64) error {
+	if m == nil {
+		return nil
+	}
+	for _, expected := range m {
+		if expected == version {
+			return nil
+		}
+	}
+	return fmt.Errorf("subscription version is %d: %w", version, ErrPreconditionFailed)
+}
+
+// mapError переводит ошибку хранилища в ошибку сервиса. Если подписка изменилась
+// между чтением и записью, а клиент передал условие, возвращается ErrPreconditionFailed.
+func (m VersionMatch) mapError(err error) error {
+	if m != nil && errors.Is(err, repository.ErrVersionConflict) {
+		return fmt.Errorf("subscription was modified concurrently: %w", ErrPreconditionFailed)
+	}
+	return mapRepoError(err)
+}